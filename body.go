@@ -0,0 +1,298 @@
+package sloggin
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"mime"
+	"path"
+	"strings"
+)
+
+// capturedBody is a body recorded by bodyReader or bodyWriter.
+type capturedBody struct {
+	body        []byte
+	tail        []byte // last bytes of the body, when RequestBodyTailSize or ResponseBodyTailSize is set
+	length      int    // bytes on the wire
+	maxSize     int    // inline limit, applied to the decoded body as well
+	contentType string
+	sniffed     bool   // contentType was detected from the body
+	encoding    string // Content-Encoding
+	binary      bool
+	form        *multipartSummary
+	proto       ProtoMessageFactory
+}
+
+// json returns true for bodies that may be JSON documents: bodies with a JSON
+// content type, and bodies sent without content type.
+func (b capturedBody) json() bool {
+	return b.sniffed || b.contentType == "" || isJSONContentType(b.contentType) || isNDJSONContentType(b.contentType)
+}
+
+func (b capturedBody) truncated() bool {
+	return b.length > len(b.body)
+}
+
+// headAndTail joins the head and the tail of the body when they overlap or are
+// contiguous. Otherwise, it returns the number of bytes missing between them.
+func (b capturedBody) headAndTail() ([]byte, []byte, int) {
+	if !b.truncated() || len(b.tail) == 0 {
+		return b.body, nil, 0
+	}
+
+	start := b.length - len(b.tail) // offset of the tail in the body
+	if start <= len(b.body) {
+		return append(append([]byte{}, b.body...), b.tail[len(b.body)-start:]...), nil, 0
+	}
+
+	return b.body, b.tail, start - len(b.body)
+}
+
+// bodyCapturePolicy returns how many bytes of a body of the given content type
+// should be captured, and whether it is binary, in which case only a preview is kept.
+func bodyCapturePolicy(contentType string, contentEncoding string, defaultMaxSize int) (int, bool) {
+	if !isSupportedContentEncoding(contentEncoding) {
+		return min(BinaryBodyPreviewSize, defaultMaxSize), true
+	}
+
+	mediaType := parseMediaType(contentType)
+
+	if len(CapturedBodyContentTypes) > 0 && !matchMediaType(CapturedBodyContentTypes, mediaType) {
+		return min(BinaryBodyPreviewSize, defaultMaxSize), true
+	}
+
+	if maxSize, found := BodyMaxSizeByContentType[mediaType]; found {
+		return maxSize, false
+	}
+
+	// wildcard patterns, the most specific one wins
+	maxSize, pattern := defaultMaxSize, ""
+	for p, size := range BodyMaxSizeByContentType {
+		if len(p) > len(pattern) && matchMediaType([]string{p}, mediaType) {
+			maxSize, pattern = size, p
+		}
+	}
+
+	return maxSize, false
+}
+
+// matchMediaType returns true if the media type matches one of the patterns,
+// eg: "application/json", "text/*" or "application/*+json".
+func matchMediaType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), mediaType); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseMediaType returns the lowercase media type, without parameters.
+func parseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(contentType, ";")
+		return strings.ToLower(strings.TrimSpace(mediaType))
+	}
+
+	return mediaType
+}
+
+// bodyAttributes formats a captured body for logging.
+//
+// Compressed bodies are decoded, and text is converted to UTF-8 from its charset.
+// Binary bodies are logged as a short preview. JSON bodies are redacted. Depending on the config, complete JSON bodies are
+// logged as structured values instead of escaped strings, and forms are summarized.
+func bodyAttributes(captured capturedBody, config Config) []slog.Attr {
+	if captured.form != nil {
+		return captured.form.attrs()
+	}
+
+	attrs := []slog.Attr{}
+	body, tail, elided := captured.headAndTail()
+	binary := captured.binary
+	truncated := len(body) < captured.length
+	size := captured.length
+
+	if truncated {
+		attrs = append(attrs, slog.Bool("body_truncated", true))
+	}
+
+	if !binary && len(body) > 0 && len(contentEncodings(captured.encoding)) > 0 {
+		decoded, incomplete, err := decodeBody(body, captured.encoding)
+		if err != nil {
+			binary = true
+		} else {
+			body = decoded
+			size = len(decoded)
+			tail = nil
+			attrs = append(attrs,
+				slog.String("body_encoding", captured.encoding),
+				slog.Int("body_wire_length", captured.length),
+				slog.Int("body_decoded_length", len(decoded)),
+			)
+
+			if captured.maxSize > 0 && len(body) > captured.maxSize {
+				body = body[:captured.maxSize]
+				incomplete = true
+			}
+			if incomplete && !truncated {
+				attrs = append(attrs, slog.Bool("body_truncated", true))
+			}
+			truncated = truncated || incomplete
+		}
+	}
+
+	raw := body
+	if !binary && !isProtobufContentType(captured.contentType) {
+		body = toUTF8(body, captured.contentType, truncated)
+		if tail != nil {
+			tail = tailToUTF8(tail, captured.contentType)
+		}
+	}
+
+	if !binary && isProtobufContentType(captured.contentType) {
+		return append(attrs, protoBodyAttributes(body, size, truncated, captured.proto, config)...)
+	}
+
+	if binary || bytes.IndexByte(body, 0) >= 0 {
+		preview := raw[:min(len(raw), BinaryBodyPreviewSize)]
+		return append(attrs, slog.String("body_preview", BinaryBodyPreviewEncoder(preview)))
+	}
+
+	if config.WithFormBody && parseMediaType(captured.contentType) == "application/x-www-form-urlencoded" {
+		return append(attrs, formAttributes(body)...)
+	}
+
+	if config.WithJSONBody && !truncated && isJSONContentType(captured.contentType) {
+		if structured, ok := structuredJSONBody(body); ok {
+			return append(attrs, slog.Any("body", structured))
+		}
+	}
+
+	if !captured.json() {
+		if tail != nil {
+			marker := fmt.Sprintf("\n...[%d bytes elided]...\n", elided)
+			return append(attrs, slog.String("body", string(body)+marker+string(tail)))
+		}
+
+		return append(attrs, slog.String("body", string(body)))
+	}
+
+	redacted, ok := redactBody(body)
+	if !ok {
+		return append(attrs, redactionFailedAttrs(body)...)
+	}
+
+	if tail != nil {
+		tail, skipped := redactBodyTail(body, tail)
+		marker := fmt.Sprintf("\n...[%d bytes elided]...\n", elided+skipped)
+		return append(attrs, slog.String("body", string(redacted)+marker+string(tail)))
+	}
+
+	return append(attrs, slog.String("body", string(redacted)))
+}
+
+// redactionFailedAttrs replaces a body that could not be redacted.
+func redactionFailedAttrs(body []byte) []slog.Attr {
+	return []slog.Attr{
+		slog.Bool("body_redaction_failed", true),
+		slog.Int("body_length", len(body)),
+	}
+}
+
+// redactBodyTail redacts the tail of a truncated body, and returns the number
+// of bytes removed from it. The tail of a JSON body is not a valid document: it
+// may start in the middle of a hidden value, or inside a hidden container
+// opened in the elided part of the body. It is kept from its first element in
+// the root container, where the names of all enclosing fields are known, and
+// dropped when the head was cut in a hidden value, or when it may contain a
+// hidden field.
+func redactBodyTail(head []byte, tail []byte) ([]byte, int) {
+	if !looksLikeJSON(head) || (len(HiddenBodyFields) == 0 && len(HiddenBodyPaths) == 0) {
+		return tail, 0
+	}
+
+	names, anyValue := hiddenBodyFieldNames()
+	if anyValue || newJSONRewriter(0).rewrite(head).cutInHidden {
+		return nil, len(tail)
+	}
+
+	next, ok := jsonTailRootElement(tail)
+	if !ok {
+		return nil, len(tail)
+	}
+
+	lower := bytes.ToLower(tail[next:])
+	for _, name := range names {
+		if bytes.Contains(lower, []byte(`"`+strings.ToLower(name)+`"`)) {
+			return nil, len(tail)
+		}
+	}
+
+	return tail[next:], next
+}
+
+// jsonTailRootElement returns the offset of the first element of the root
+// container in the last bytes of a JSON document, ie: after the first comma
+// that is neither in a string nor in a nested container.
+func jsonTailRootElement(tail []byte) (int, bool) {
+	// The document ends outside of a string: a byte is in a string when an odd
+	// number of quotes follows it. The escaping of a quote preceded by
+	// backslashes up to the start of the tail is unknown.
+	quotes := make([]bool, len(tail)) // unescaped quotes
+	count := 0
+	for i, c := range tail {
+		if c != '"' {
+			continue
+		}
+
+		backslashes := 0
+		for j := i - 1; j >= 0 && tail[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes == i && i > 0 {
+			return 0, false
+		}
+
+		if backslashes%2 == 0 {
+			quotes[i] = true
+			count++
+		}
+	}
+
+	// depth relative to the start of the tail, at every comma outside strings
+	inString := count%2 == 1
+	depth := 0
+	commas := []int{}
+	depths := []int{}
+	for i, c := range tail {
+		if quotes[i] {
+			inString = !inString
+			continue
+		}
+		if inString {
+			continue
+		}
+
+		switch c {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		case ',':
+			commas = append(commas, i)
+			depths = append(depths, depth)
+		}
+	}
+
+	// the document ends at the depth of the root container's parent
+	for i, comma := range commas {
+		if depths[i] == depth+1 {
+			return comma + 1, true
+		}
+	}
+
+	return 0, false
+}
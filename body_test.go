@@ -0,0 +1,281 @@
+package sloggin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHeadAndTail(t *testing.T) {
+	t.Parallel()
+
+	body := []byte("0123456789abcdefghij")
+
+	tests := map[string]struct {
+		head     int
+		tail     int
+		expected string
+		rest     string
+		elided   int
+	}{
+		"complete":   {head: 20, tail: 5, expected: "0123456789abcdefghij"},
+		"no tail":    {head: 5, tail: 0, expected: "01234"},
+		"overlap":    {head: 12, tail: 10, expected: "0123456789abcdefghij"},
+		"contiguous": {head: 10, tail: 10, expected: "0123456789abcdefghij"},
+		"gap":        {head: 5, tail: 5, expected: "01234", rest: "fghij", elided: 10},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			captured := capturedBody{
+				body:   body[:tt.head],
+				length: len(body),
+			}
+			if tt.tail > 0 {
+				captured.tail = body[len(body)-tt.tail:]
+			}
+
+			head, tail, elided := captured.headAndTail()
+			if string(head) != tt.expected || string(tail) != tt.rest || elided != tt.elided {
+				t.Fatalf("got %q %q %d, expected %q %q %d", head, tail, elided, tt.expected, tt.rest, tt.elided)
+			}
+		})
+	}
+}
+
+func TestBodyAttributesTail(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body     string
+		head     int
+		tail     int
+		expected string
+	}{
+		"text": {
+			body:     "hello beautiful world",
+			head:     5,
+			tail:     5,
+			expected: "hello\n...[11 bytes elided]...\nworld",
+		},
+		"head cut in hidden container": {
+			body:     `{"secret":{"pad":"` + strings.Repeat("x", 40) + `","pin":"1234"},"ok":1}`,
+			head:     20,
+			tail:     30,
+			expected: `{"secret":"[REDACTED]"` + "\n...[61 bytes elided]...\n",
+		},
+		"hidden container in the gap": {
+			body:     `{"a":1,"b":"` + strings.Repeat("x", 20) + `","secret":{"pad":"xxxx","pin":"1234"},"ok":1}`,
+			head:     20,
+			tail:     30,
+			expected: `{"a":1,"b":"xxxxxxxx` + "\n...[51 bytes elided]...\n" + `"ok":1}`,
+		},
+		"tail starts in a string": {
+			body:     `{"a":"` + strings.Repeat("x", 30) + `","b":"x,{y","c":[1,2],"d":3}`,
+			head:     10,
+			tail:     30,
+			expected: `{"a":"xxxx` + "\n...[28 bytes elided]...\n" + `"b":"x,{y","c":[1,2],"d":3}`,
+		},
+		"hidden field in the tail": {
+			body:     `{"a":"` + strings.Repeat("x", 30) + `","b":1,"password":"hunter2"}`,
+			head:     10,
+			tail:     30,
+			expected: `{"a":"xxxx` + "\n...[55 bytes elided]...\n",
+		},
+		"no root element in the tail": {
+			body:     `{"a":{"b":"` + strings.Repeat("x", 30) + `","c":"1234"}}`,
+			head:     10,
+			tail:     20,
+			expected: `{"a":{"b":` + "\n...[45 bytes elided]...\n",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			captured := capturedBody{
+				body:        []byte(tt.body[:tt.head]),
+				tail:        []byte(tt.body[len(tt.body)-tt.tail:]),
+				length:      len(tt.body),
+				contentType: "application/json",
+			}
+
+			attrs := bodyAttributes(captured, Config{})
+			got := map[string]string{}
+			for _, attr := range attrs {
+				got[attr.Key] = attr.Value.String()
+			}
+
+			if got["body_truncated"] != "true" {
+				t.Fatalf("body_truncated missing: %v", attrs)
+			}
+			if got["body"] != tt.expected {
+				t.Fatalf("got %q, expected %q", got["body"], tt.expected)
+			}
+			if strings.Contains(got["body"], "1234") || strings.Contains(got["body"], "hunter2") {
+				t.Fatalf("secret leaked: %q", got["body"])
+			}
+		})
+	}
+}
+
+func TestBodyAttributesEncoding(t *testing.T) {
+	t.Parallel()
+
+	plain := strings.Repeat(`{"message":"hello world"}`, 100)
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write([]byte(plain))
+	_ = zw.Close()
+
+	captured := capturedBody{
+		body:        compressed.Bytes(),
+		length:      compressed.Len(),
+		contentType: "text/plain",
+		encoding:    "gzip",
+	}
+
+	got := map[string]string{}
+	for _, attr := range bodyAttributes(captured, Config{}) {
+		got[attr.Key] = attr.Value.String()
+	}
+
+	if got["body_encoding"] != "gzip" || got["body_wire_length"] != strconv.Itoa(compressed.Len()) || got["body_decoded_length"] != strconv.Itoa(len(plain)) {
+		t.Fatalf("unexpected attributes: %v", got)
+	}
+	if got["body"] != plain {
+		t.Fatalf("got %q, expected %q", got["body"], plain)
+	}
+
+	// partly captured body
+	captured.body = compressed.Bytes()[:compressed.Len()/2]
+
+	got = map[string]string{}
+	for _, attr := range bodyAttributes(captured, Config{}) {
+		got[attr.Key] = attr.Value.String()
+	}
+
+	if got["body_truncated"] != "true" || got["body_wire_length"] != strconv.Itoa(compressed.Len()) {
+		t.Fatalf("unexpected attributes: %v", got)
+	}
+}
+
+func TestBodyAttributesDecodedLimit(t *testing.T) {
+	t.Parallel()
+
+	plain := strings.Repeat("hello world ", 1000)
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write([]byte(plain))
+	_ = zw.Close()
+
+	captured := capturedBody{
+		body:        compressed.Bytes(),
+		length:      compressed.Len(),
+		maxSize:     100,
+		contentType: "text/plain",
+		encoding:    "gzip",
+	}
+	if compressed.Len() > captured.maxSize {
+		t.Fatalf("compressed body too large for the test: %d", compressed.Len())
+	}
+
+	got := map[string]string{}
+	for _, attr := range bodyAttributes(captured, Config{}) {
+		got[attr.Key] = attr.Value.String()
+	}
+
+	if got["body"] != plain[:100] || got["body_truncated"] != "true" || got["body_decoded_length"] != strconv.Itoa(len(plain)) {
+		t.Fatalf("unexpected attributes: %v", got)
+	}
+}
+
+func TestBodyCapturePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		contentType string
+		encoding    string
+		maxSize     int
+		binary      bool
+	}{
+		"json":                 {contentType: "application/json; charset=utf-8", maxSize: 1000},
+		"json suffix":          {contentType: "application/problem+json", maxSize: 1000},
+		"text wildcard":        {contentType: "text/csv", maxSize: 1000},
+		"case insensitive":     {contentType: "Application/JSON", maxSize: 1000},
+		"image":                {contentType: "image/png", maxSize: 32, binary: true},
+		"octet stream":         {contentType: "application/octet-stream", maxSize: 32, binary: true},
+		"supported encoding":   {contentType: "application/json", encoding: "gzip", maxSize: 1000},
+		"unsupported encoding": {contentType: "application/json", encoding: "compress", maxSize: 32, binary: true},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			maxSize, binary := bodyCapturePolicy(tt.contentType, tt.encoding, 1000)
+			if maxSize != tt.maxSize || binary != tt.binary {
+				t.Fatalf("got %d %v, expected %d %v", maxSize, binary, tt.maxSize, tt.binary)
+			}
+		})
+	}
+}
+
+// not parallel: updates global settings
+func TestBodyMaxSizeByContentType(t *testing.T) {
+	sizes := BodyMaxSizeByContentType
+	BodyMaxSizeByContentType = map[string]int{
+		"text/*":    10,
+		"text/html": 20,
+	}
+	defer func() {
+		BodyMaxSizeByContentType = sizes
+	}()
+
+	for contentType, expected := range map[string]int{"text/plain": 10, "text/html": 20, "application/json": 1000} {
+		if maxSize, _ := bodyCapturePolicy(contentType, "", 1000); maxSize != expected {
+			t.Errorf("%s: got %d, expected %d", contentType, maxSize, expected)
+		}
+	}
+}
+
+func TestBinaryBodyRecord(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	config := DefaultConfig()
+	config.WithResponseBody = true
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	router.GET("/logo.png", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", png)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/logo.png", nil))
+
+	output := logs.String()
+	expected := "response.body_preview=" + hex.EncodeToString(png[:BinaryBodyPreviewSize])
+	if !strings.Contains(output, expected) || !strings.Contains(output, "response.length=108") || strings.Contains(output, "response.body=") {
+		t.Fatalf("expected a binary preview:\n%s", output)
+	}
+}
@@ -0,0 +1,112 @@
+package sloggin
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// toUTF8 converts a body to valid UTF-8, according to the charset parameter of
+// its content type (eg: ISO-8859-1, Shift_JIS). A truncated body never ends
+// with a partial character, and invalid sequences are replaced by U+FFFD.
+func toUTF8(body []byte, contentType string, truncated bool) []byte {
+	if dec := charsetDecoder(contentType); dec != nil {
+		if decoded, err := decodeCharset(dec, body, truncated); err == nil {
+			body = decoded
+		}
+	}
+
+	if truncated {
+		body = trimPartialRune(body)
+	}
+
+	if !utf8.Valid(body) {
+		body = bytes.ToValidUTF8(body, []byte(string(utf8.RuneError)))
+	}
+
+	return body
+}
+
+// tailToUTF8 converts the tail of a truncated body, which may start in the
+// middle of a character.
+func tailToUTF8(tail []byte, contentType string) []byte {
+	if charsetDecoder(contentType) == nil {
+		for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+			tail = tail[1:]
+		}
+	}
+
+	return toUTF8(tail, contentType, false)
+}
+
+// charsetDecoder returns the decoder of the charset of a content type, or nil
+// for UTF-8, ASCII and unknown charsets.
+func charsetDecoder(contentType string) *encoding.Decoder {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	charset := strings.ToLower(strings.TrimSpace(params["charset"]))
+	switch charset {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil
+	}
+
+	if name, _ := htmlindex.Name(enc); name == "utf-8" {
+		return nil
+	}
+
+	return enc.NewDecoder()
+}
+
+// decodeCharset transcodes a body to UTF-8. When the body is truncated, a
+// trailing partial character is dropped instead of being replaced.
+func decodeCharset(dec *encoding.Decoder, body []byte, truncated bool) ([]byte, error) {
+	dec.Reset()
+
+	out := make([]byte, 0, len(body)+len(body)/2)
+	buf := make([]byte, 4096)
+
+	for {
+		nDst, nSrc, err := dec.Transform(buf, body, !truncated)
+		out = append(out, buf[:nDst]...)
+		body = body[nSrc:]
+
+		switch {
+		case err == nil:
+			return out, nil
+		case errors.Is(err, transform.ErrShortDst):
+			continue
+		case errors.Is(err, transform.ErrShortSrc) && truncated:
+			return out, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// trimPartialRune removes a partial UTF-8 character at the end of b.
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+
+	return b
+}
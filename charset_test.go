@@ -0,0 +1,72 @@
+package sloggin
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestToUTF8(t *testing.T) {
+	t.Parallel()
+
+	sjis, _ := japanese.ShiftJIS.NewEncoder().Bytes([]byte("こんにちは"))
+
+	tests := map[string]struct {
+		body        []byte
+		contentType string
+		truncated   bool
+		expected    string
+	}{
+		"shift_jis":                 {body: sjis, contentType: "text/plain; charset=Shift_JIS", expected: "こんにちは"},
+		"shift_jis truncated rune":  {body: sjis[:5], contentType: "text/plain; charset=shift_jis", truncated: true, expected: "こん"},
+		"shift_jis invalid":         {body: sjis[:5], contentType: "text/plain; charset=shift_jis", expected: "こん�"},
+		"iso-8859-1":                {body: []byte("caf\xe9"), contentType: "text/plain; charset=ISO-8859-1", expected: "café"},
+		"utf-8":                     {body: []byte("café"), contentType: "text/plain; charset=utf-8", expected: "café"},
+		"utf-8 truncated rune":      {body: []byte("caf\xc3"), contentType: "application/json", truncated: true, expected: "caf"},
+		"invalid utf-8":             {body: []byte("a\xffb"), contentType: "text/plain", expected: "a�b"},
+		"unknown charset":           {body: []byte("hello"), contentType: "text/plain; charset=x-unknown", expected: "hello"},
+		"invalid with unknown type": {body: []byte("a\xff"), contentType: "", expected: "a�"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := string(toUTF8(tt.body, tt.contentType, tt.truncated)); got != tt.expected {
+				t.Fatalf("got %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTailToUTF8(t *testing.T) {
+	t.Parallel()
+
+	// starts in the middle of "é"
+	if got := string(tailToUTF8([]byte("\xa9t\xc3\xa9"), "text/plain")); got != "té" {
+		t.Fatalf("got %q, expected %q", got, "té")
+	}
+}
+
+func TestBodyAttributesCharset(t *testing.T) {
+	t.Parallel()
+
+	sjis, _ := japanese.ShiftJIS.NewEncoder().Bytes([]byte("こんにちは"))
+
+	// capture limit in the middle of a character
+	captured := capturedBody{
+		body:        sjis[:7],
+		length:      len(sjis),
+		contentType: "text/plain; charset=Shift_JIS",
+	}
+
+	got := map[string]string{}
+	for _, attr := range bodyAttributes(captured, Config{}) {
+		got[attr.Key] = attr.Value.String()
+	}
+
+	if got["body"] != "こんに" || got["body_truncated"] != "true" {
+		t.Fatalf("unexpected attributes: %v", got)
+	}
+}
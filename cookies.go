@@ -0,0 +1,86 @@
+package sloggin
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maskCookie masks a cookie value, using MaskedCookies or DefaultCookieMasker.
+func maskCookie(name string, value string) string {
+	if mask, found := MaskedCookies[strings.ToLower(name)]; found && mask != nil {
+		return mask(value)
+	}
+
+	if DefaultCookieMasker != nil {
+		return DefaultCookieMasker(value)
+	}
+
+	return RedactedValue
+}
+
+// extractRequestCookies turns the `Cookie` header into slog attributes.
+func extractRequestCookies(cookies []*http.Cookie) []any {
+	kv := make([]any, 0, len(cookies))
+	for _, cookie := range cookies {
+		kv = append(kv, slog.String(cookie.Name, maskCookie(cookie.Name, cookie.Value)))
+	}
+
+	return kv
+}
+
+// extractResponseCookies turns the `Set-Cookie` headers into slog attributes.
+func extractResponseCookies(header http.Header) []any {
+	cookies := (&http.Response{Header: header}).Cookies()
+
+	kv := make([]any, 0, len(cookies))
+	for _, cookie := range cookies {
+		attrs := []any{
+			slog.String("value", maskCookie(cookie.Name, cookie.Value)),
+		}
+
+		if cookie.Path != "" {
+			attrs = append(attrs, slog.String("path", cookie.Path))
+		}
+
+		if cookie.Domain != "" {
+			attrs = append(attrs, slog.String("domain", cookie.Domain))
+		}
+
+		if !cookie.Expires.IsZero() {
+			attrs = append(attrs, slog.Time("expires", cookie.Expires.UTC()))
+		}
+
+		if cookie.MaxAge != 0 {
+			// MaxAge<0 means "delete cookie now", ie: `Max-Age` of 0 or below.
+			attrs = append(attrs, slog.Duration("max_age", time.Duration(max(cookie.MaxAge, 0))*time.Second))
+		}
+
+		attrs = append(attrs,
+			slog.Bool("secure", cookie.Secure),
+			slog.Bool("http_only", cookie.HttpOnly),
+		)
+
+		if sameSite := sameSiteString(cookie.SameSite); sameSite != "" {
+			attrs = append(attrs, slog.String("same_site", sameSite))
+		}
+
+		kv = append(kv, slog.Group(cookie.Name, attrs...))
+	}
+
+	return kv
+}
+
+func sameSiteString(sameSite http.SameSite) string {
+	switch sameSite {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,94 @@
+package sloggin
+
+import (
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+// not parallel: updates global settings
+func TestExtractRequestCookies(t *testing.T) {
+	masked, defaultMasker := MaskedCookies, DefaultCookieMasker
+	defer func() {
+		MaskedCookies, DefaultCookieMasker = masked, defaultMasker
+	}()
+
+	cookies := []*http.Cookie{
+		{Name: "session_id", Value: "test"},
+		{Name: "csrf_token", Value: "0123456789f00d"},
+		{Name: "theme", Value: "dark"},
+	}
+
+	MaskedCookies = map[string]Masker{
+		"session_id": MaskSHA256,
+		"csrf_token": MaskLast4,
+	}
+
+	got := slog.GroupValue(attrsFromArgs(extractRequestCookies(cookies))...).String()
+	expected := "[session_id=sha256:9f86d081884c7d65 csrf_token=****f00d theme=[REDACTED]]"
+	if got != expected {
+		t.Fatalf("got %s, expected %s", got, expected)
+	}
+
+	// per-name maskers take precedence over the default one
+	DefaultCookieMasker = func(value string) string { return value }
+	got = slog.GroupValue(attrsFromArgs(extractRequestCookies(cookies))...).String()
+	expected = "[session_id=sha256:9f86d081884c7d65 csrf_token=****f00d theme=dark]"
+	if got != expected {
+		t.Fatalf("got %s, expected %s", got, expected)
+	}
+
+	// no default masker
+	DefaultCookieMasker = nil
+	got = slog.GroupValue(attrsFromArgs(extractRequestCookies(cookies[2:]))...).String()
+	if got != "[theme=[REDACTED]]" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestExtractResponseCookies(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		setCookie string
+		expected  string
+	}{
+		"minimal": {
+			setCookie: "a=1",
+			expected:  "[a=[value=[REDACTED] secure=false http_only=false]]",
+		},
+		"attributes": {
+			setCookie: "a=1; Path=/app; Domain=example.com; Expires=Wed, 21 Oct 2015 07:28:00 GMT; Max-Age=3600; Secure; HttpOnly; SameSite=Strict",
+			expected:  "[a=[value=[REDACTED] path=/app domain=example.com expires=2015-10-21 07:28:00 +0000 UTC max_age=1h0m0s secure=true http_only=true same_site=Strict]]",
+		},
+		"empty path": {
+			setCookie: "a=1; Path=; SameSite=Lax",
+			expected:  "[a=[value=[REDACTED] secure=false http_only=false same_site=Lax]]",
+		},
+		"max-age zero": {
+			setCookie: "a=; Max-Age=0",
+			expected:  "[a=[value=[REDACTED] max_age=0s secure=false http_only=false]]",
+		},
+		"max-age negative": {
+			setCookie: "a=; Max-Age=-1; SameSite=None; Secure",
+			expected:  "[a=[value=[REDACTED] max_age=0s secure=true http_only=false same_site=None]]",
+		},
+		"invalid same-site": {
+			setCookie: "a=1; SameSite=Bogus",
+			expected:  "[a=[value=[REDACTED] secure=false http_only=false]]",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			header := http.Header{"Set-Cookie": {tt.setCookie}}
+			got := slog.GroupValue(attrsFromArgs(extractResponseCookies(header))...).String()
+			if got != tt.expected {
+				t.Fatalf("got %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
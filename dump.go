@@ -1,48 +1,185 @@
 package sloggin
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/hex"
+	"errors"
+	"hash"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// buffers larger than this are not returned to the pool
+const bodyBufferPoolMaxSize = 1024 * 1024 // 1MB
+
+var bodyBufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func newBodyBuffer() *bytes.Buffer {
+	buf := bodyBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func releaseBodyBuffer(buf *bytes.Buffer) {
+	if buf != nil && buf.Cap() <= bodyBufferPoolMaxSize {
+		bodyBufferPool.Put(buf)
+	}
+}
+
+// captureBody appends b to body, so that body holds exactly the first maxSize
+// bytes of the stream.
+func captureBody(body *bytes.Buffer, b []byte, maxSize int) {
+	if remaining := maxSize - body.Len(); remaining > 0 {
+		body.Write(b[:min(remaining, len(b))])
+	}
+}
+
 var _ http.ResponseWriter = (*bodyWriter)(nil)
 var _ http.Flusher = (*bodyWriter)(nil)
 var _ http.Hijacker = (*bodyWriter)(nil)
 var _ io.ReaderFrom = (*bodyWriter)(nil)
+var _ io.StringWriter = (*bodyWriter)(nil)
 
 type bodyWriter struct {
 	gin.ResponseWriter
-	body    *bytes.Buffer
-	maxSize int
-	bytes   int
+	body        *bytes.Buffer
+	tail        *ringBuffer
+	spill       *bodySpill
+	hash        hash.Hash
+	maxSize     int
+	bytes       atomic.Int64 // read concurrently by InflightRegistry
+	contentType string
+	sniffed     bool // no Content-Type header
+	encoding    string
+	binary      bool
+	decided     bool
+	err         error // first write error
+
+	// streaming metrics
+	firstByte time.Time // headers sent
+	lastWrite time.Time
+	writes    int
+	flushes   int
+
+	sse        *sseTracker
+	sseDecided bool
+
+	hijacked            bool
+	onHijackedConnClose func(*hijackedConn)
 }
 
 // implements gin.ResponseWriter
 func (w *bodyWriter) Write(b []byte) (int, error) {
-	length := len(b)
+	if w.body != nil && !w.decided {
+		w.decide(b)
+	}
+	w.decideSSE()
+
+	begin := time.Now()
+	n, err := w.ResponseWriter.Write(b)
+	w.record(b[:n])
+	w.recordError(err)
+	w.recordWrite(begin)
+	return n, err
+}
 
+// implements gin.ResponseWriter
+func (w *bodyWriter) WriteHeaderNow() {
+	w.decideSSE()
+	w.ResponseWriter.WriteHeaderNow()
+	w.recordFirstByte(time.Now())
+}
+
+// implements http.Hijacker
+func (w *bodyWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err != nil {
+		return conn, rw, err
+	}
+
+	w.hijacked = true
+	if w.onHijackedConnClose != nil {
+		conn, rw = wrapHijackedConn(conn, rw, w.onHijackedConnClose)
+	}
+
+	return conn, rw, nil
+}
+
+// implements http.Flusher
+func (w *bodyWriter) Flush() {
+	w.decideSSE()
+	w.ResponseWriter.Flush()
+	w.recordFirstByte(time.Now())
+	w.flushes++
+}
+
+// recordWrite records a write started at begin.
+func (w *bodyWriter) recordWrite(begin time.Time) {
+	w.recordFirstByte(begin)
+	w.lastWrite = time.Now()
+	w.writes++
+}
+
+func (w *bodyWriter) recordFirstByte(now time.Time) {
+	if w.firstByte.IsZero() {
+		w.firstByte = now
+	}
+}
+
+// record captures bytes actually written to the client.
+func (w *bodyWriter) record(b []byte) {
 	if w.body != nil {
-		if w.body.Len()+length > w.maxSize {
-			w.body.Truncate(min(w.maxSize, length, w.body.Len()))
-			w.body.Write(b[:min(w.maxSize-w.body.Len(), length)])
-		} else {
-			w.body.Write(b)
+		w.spill.write(w.body.Bytes(), b, w.maxSize)
+		captureBody(w.body, b, w.maxSize)
+
+		if w.tail != nil {
+			_, _ = w.tail.Write(b)
 		}
 	}
 
-	w.bytes += length //nolint:staticcheck
-	return w.ResponseWriter.Write(b)
+	if w.hash != nil {
+		w.hash.Write(b)
+	}
+
+	if w.sse != nil {
+		_, _ = w.sse.Write(b)
+	}
+
+	w.bytes.Add(int64(len(b)))
+}
+
+func (w *bodyWriter) recordError(err error) {
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// implements io.StringWriter
+func (w *bodyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
 }
 
 // implements io.ReaderFrom
 func (w *bodyWriter) ReadFrom(r io.Reader) (int64, error) {
-	if w.body == nil {
+	if w.body == nil && w.hash == nil && w.sse == nil {
 		if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+			begin := time.Now()
 			n, err := rf.ReadFrom(r)
-			w.bytes += int(n)
+			w.bytes.Add(n)
+			w.recordError(err)
+			w.recordWrite(begin)
 			return n, err
 		}
 	}
@@ -50,51 +187,362 @@ func (w *bodyWriter) ReadFrom(r io.Reader) (int64, error) {
 	return io.Copy(struct{ io.Writer }{w}, r)
 }
 
-func newBodyWriter(writer gin.ResponseWriter, maxSize int, recordBody bool) *bodyWriter {
+// length returns the number of bytes written so far.
+func (w *bodyWriter) length() int {
+	return int(w.bytes.Load())
+}
+
+// throughput returns the download speed, in bytes per second.
+func (w *bodyWriter) throughput() (int64, bool) {
+	return throughput(w.length(), w.lastWrite.Sub(w.firstByte))
+}
+
+// decideSSE starts tracking events, if the response is an event stream. The
+// content type must be set before headers are sent.
+func (w *bodyWriter) decideSSE() {
+	if w.sse == nil || w.sseDecided {
+		return
+	}
+
+	w.sseDecided = true
+	if isEventStream(w.Header().Get("Content-Type")) {
+		w.sse.activate(w.Status())
+	}
+}
+
+// Unwrap returns the wrapped writer, so that http.ResponseController can reach
+// optional methods (eg: SetWriteDeadline, EnableFullDuplex).
+func (w *bodyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide applies the capture policy, once response headers are known.
+func (w *bodyWriter) decide(b []byte) {
+	w.decided = true
+	w.contentType = w.Header().Get("Content-Type")
+	w.encoding = w.Header().Get("Content-Encoding")
+	w.sniffed = w.contentType == ""
+	if w.sniffed && len(contentEncodings(w.encoding)) == 0 {
+		w.contentType = http.DetectContentType(b)
+	}
+	w.maxSize, w.binary = bodyCapturePolicy(w.contentType, w.encoding, w.maxSize)
+}
+
+func (w *bodyWriter) captured() capturedBody {
+	return capturedBody{
+		body:        w.body.Bytes(),
+		tail:        tailBytes(w.tail),
+		length:      w.length(),
+		maxSize:     w.maxSize,
+		contentType: w.contentType,
+		sniffed:     w.sniffed,
+		encoding:    w.encoding,
+		binary:      w.binary,
+	}
+}
+
+// release returns the capture buffer to the pool. Later writes (eg: from an
+// outer middleware) are not captured anymore.
+func (w *bodyWriter) release() {
+	releaseBodyBuffer(w.body)
+	w.body = nil
+	w.tail = nil
+	w.spill.release()
+	w.spill = nil
+}
+
+func newBodyWriter(writer gin.ResponseWriter, maxSize int, tailSize int, recordBody bool, spill *bodySpill, withHash bool) *bodyWriter {
 	var body *bytes.Buffer
-	if recordBody {
-		body = bytes.NewBufferString("")
+	var tail *ringBuffer
+	if !recordBody {
+		spill = nil
+	} else {
+		body = newBodyBuffer()
+		if tailSize > 0 {
+			tail = newRingBuffer(tailSize)
+		}
 	}
 
 	return &bodyWriter{
 		ResponseWriter: writer,
 		body:           body,
+		tail:           tail,
+		spill:          spill,
+		hash:           newBodyHash(withHash),
 		maxSize:        maxSize,
-		bytes:          0,
 	}
 }
 
 type bodyReader struct {
 	io.ReadCloser
-	body    *bytes.Buffer
-	maxSize int
-	bytes   int
+	body        *bytes.Buffer
+	tail        *ringBuffer
+	spill       *bodySpill
+	hash        hash.Hash
+	maxSize     int
+	bytes       atomic.Int64 // read concurrently by InflightRegistry
+	contentType string
+	sniffed     bool // no Content-Type header
+	encoding    string
+	binary      bool
+	decided     bool
+	form        *multipartSummary
+	eof         bool
+	err         error // first read error, other than io.EOF
+	unavailable bool  // the remainder was discarded by net/http before drain
+	firstRead   time.Time
+	lastRead    time.Time
 }
 
 // implements io.Reader
 func (r *bodyReader) Read(b []byte) (int, error) {
+	begin := time.Now()
 	n, err := r.ReadCloser.Read(b)
-	if r.body != nil && r.body.Len() < r.maxSize {
-		if r.body.Len()+n > r.maxSize {
-			r.body.Write(b[:min(r.maxSize-r.body.Len(), n)])
-		} else {
-			r.body.Write(b[:n])
+	if n > 0 {
+		if r.firstRead.IsZero() {
+			r.firstRead = begin
 		}
+		r.lastRead = time.Now()
+	}
+	if r.form != nil {
+		_, _ = r.form.Write(b[:n])
+	}
+	if r.body != nil && !r.decided && n > 0 {
+		r.decide(b[:n])
+	}
+	if r.body != nil && n > 0 {
+		r.spill.write(r.body.Bytes(), b[:n], r.maxSize)
+	}
+	if r.body != nil {
+		captureBody(r.body, b[:n], r.maxSize)
+	}
+	if r.tail != nil {
+		_, _ = r.tail.Write(b[:n])
+	}
+	if r.hash != nil {
+		r.hash.Write(b[:n])
+	}
+	r.bytes.Add(int64(n))
+	r.eof = r.eof || errors.Is(err, io.EOF)
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
 	}
-	r.bytes += n
 	return n, err
 }
 
-func newBodyReader(reader io.ReadCloser, maxSize int, recordBody bool) *bodyReader {
+// length returns the number of bytes read so far.
+func (r *bodyReader) length() int {
+	return int(r.bytes.Load())
+}
+
+// complete returns true when the whole body was read, given the Content-Length
+// of the request (-1 when unknown).
+func (r *bodyReader) complete(contentLength int64) bool {
+	if contentLength >= 0 {
+		return r.bytes.Load() == contentLength
+	}
+
+	return r.eof
+}
+
+// throughput returns the upload speed, in bytes per second.
+func (r *bodyReader) throughput() (int64, bool) {
+	return throughput(r.length(), r.lastRead.Sub(r.firstRead))
+}
+
+// drain reads the remainder of a body the handler did not read entirely, up
+// to maxSize bytes, for at most timeout. It returns the number of bytes read
+// by the handler, and the number of bytes sent by the client, as far as known.
+//
+// The body is not read when the connection does not support read deadlines.
+// Once the response headers are sent, net/http discards the unread body of
+// HTTP/1.x requests itself: it cannot be read anymore, and unavailable is set.
+func (r *bodyReader) drain(req *http.Request, writer http.ResponseWriter, maxSize int64, timeout time.Duration) (int, int64) {
+	readByHandler := r.length()
+	if r.eof {
+		return readByHandler, r.bytes.Load()
+	}
+
+	// Without a read deadline, a stalled client would block the request: the
+	// body is not read, eg: with writers wrapped without Unwrap, or recorders.
+	rc := http.NewResponseController(writer)
+	if err := rc.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return readByHandler, max(r.bytes.Load(), req.ContentLength)
+	}
+	defer func() {
+		_ = rc.SetReadDeadline(time.Time{})
+	}()
+
+	// errors caused by the deadline are not the handler's
+	err := r.err
+	_, drainErr := io.CopyN(io.Discard, r, maxSize)
+	r.err = err
+
+	if errors.Is(drainErr, http.ErrBodyReadAfterClose) {
+		r.unavailable = true
+		return readByHandler, max(r.bytes.Load(), req.ContentLength)
+	}
+
+	if r.eof {
+		return readByHandler, r.bytes.Load()
+	}
+
+	return readByHandler, max(r.bytes.Load(), req.ContentLength)
+}
+
+// decide applies the capture policy. The request content type is known
+// upfront, the body is sniffed only when it is missing.
+func (r *bodyReader) decide(b []byte) {
+	r.decided = true
+	r.sniffed = r.contentType == ""
+	if r.sniffed && len(contentEncodings(r.encoding)) == 0 {
+		r.contentType = http.DetectContentType(b)
+	}
+	r.maxSize, r.binary = bodyCapturePolicy(r.contentType, r.encoding, r.maxSize)
+}
+
+func (r *bodyReader) captured() capturedBody {
+	return capturedBody{
+		body:        r.body.Bytes(),
+		tail:        tailBytes(r.tail),
+		length:      r.length(),
+		maxSize:     r.maxSize,
+		contentType: r.contentType,
+		sniffed:     r.sniffed,
+		encoding:    r.encoding,
+		binary:      r.binary,
+		form:        r.form,
+	}
+}
+
+// release returns the capture buffer to the pool.
+func (r *bodyReader) release() {
+	releaseBodyBuffer(r.body)
+	r.body = nil
+	r.tail = nil
+	r.form = nil
+	r.spill.release()
+	r.spill = nil
+}
+
+func newBodyReader(reader io.ReadCloser, maxSize int, tailSize int, recordBody bool, header http.Header, summarizeForm bool, spill *bodySpill, withHash bool) *bodyReader {
 	var body *bytes.Buffer
-	if recordBody {
-		body = bytes.NewBufferString("")
+	var tail *ringBuffer
+	if !recordBody {
+		spill = nil
+	} else {
+		body = newBodyBuffer()
+		if tailSize > 0 {
+			tail = newRingBuffer(tailSize)
+		}
+	}
+
+	r := &bodyReader{
+		ReadCloser:  reader,
+		body:        body,
+		tail:        tail,
+		spill:       spill,
+		hash:        newBodyHash(withHash),
+		maxSize:     maxSize,
+		contentType: header.Get("Content-Type"),
+		encoding:    header.Get("Content-Encoding"),
+	}
+
+	if recordBody && r.contentType != "" {
+		r.decide(nil)
 	}
 
-	return &bodyReader{
-		ReadCloser: reader,
-		body:       body,
-		maxSize:    maxSize,
-		bytes:      0,
+	// multipart bodies are summarized instead of being captured
+	if boundary, ok := multipartBoundary(r.contentType); recordBody && summarizeForm && ok && len(contentEncodings(r.encoding)) == 0 {
+		r.form = newMultipartSummary(boundary)
+		r.maxSize = 0
+		r.tail = nil
 	}
+
+	return r
+}
+
+func tailBytes(tail *ringBuffer) []byte {
+	if tail == nil {
+		return nil
+	}
+
+	return tail.Bytes()
+}
+
+func newBodyHash(withHash bool) hash.Hash {
+	if !withHash || BodyHashFunc == nil {
+		return nil
+	}
+
+	return BodyHashFunc()
+}
+
+// hashAttrs returns the fingerprint of the full body. A partly read body has
+// no fingerprint: body_hash_partial=true is returned instead.
+func hashAttrs(h hash.Hash, complete bool) []slog.Attr {
+	if h == nil {
+		return []slog.Attr{}
+	}
+
+	if !complete {
+		return []slog.Attr{slog.Bool("body_hash_partial", true)}
+	}
+
+	return []slog.Attr{slog.String(BodyHashKey, hex.EncodeToString(h.Sum(nil)))}
+}
+
+// bodyErrorAttrs describes the error returned to the handler while reading the body.
+func bodyErrorAttrs(err error) []slog.Attr {
+	if err == nil {
+		return []slog.Attr{}
+	}
+
+	attrs := []slog.Attr{slog.String("body_error", err.Error())}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		attrs = append(attrs,
+			slog.Bool("body_too_large", true),
+			slog.Int64("body_limit", maxBytesErr.Limit),
+		)
+	}
+
+	return attrs
+}
+
+// throughput returns bytes per second, when the transfer duration is known.
+func throughput(bytes int, duration time.Duration) (int64, bool) {
+	if bytes <= 0 || duration <= 0 {
+		return 0, false
+	}
+
+	return int64(float64(bytes) / duration.Seconds()), true
+}
+
+// isSlowTransfer reports whether a large transfer fell below SlowTransferMinThroughput.
+func isSlowTransfer(bytes int, bps int64, ok bool) bool {
+	return ok && SlowTransferMinThroughput > 0 && bytes >= SlowTransferMinSize && bps < SlowTransferMinThroughput
+}
+
+// streamingAttrs describes how the response was written, relative to the start of the request.
+func streamingAttrs(w *bodyWriter, start time.Time) []slog.Attr {
+	attrs := make([]slog.Attr, 0, 5)
+	if !w.firstByte.IsZero() {
+		attrs = append(attrs, slog.Duration("time_to_first_byte", w.firstByte.Sub(start)))
+	}
+
+	attrs = append(attrs,
+		slog.Int("writes", w.writes),
+		slog.Int("flushes", w.flushes),
+	)
+
+	if !w.lastWrite.IsZero() {
+		attrs = append(attrs,
+			slog.Time("last_write", w.lastWrite.UTC()),
+			slog.Duration("write_duration", w.lastWrite.Sub(w.firstByte)),
+		)
+	}
+
+	return attrs
 }
@@ -0,0 +1,410 @@
+package sloggin
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// chunks splits data according to sizes, reusing sizes cyclically.
+// Zero sizes produce empty chunks.
+func chunks(data []byte, sizes []byte) [][]byte {
+	if len(bytes.Trim(sizes, "\x00")) == 0 {
+		return [][]byte{data}
+	}
+
+	out := [][]byte{}
+	for i := 0; len(data) > 0; i++ {
+		size := min(int(sizes[i%len(sizes)]), len(data))
+		out = append(out, data[:size])
+		data = data[size:]
+	}
+
+	return out
+}
+
+// chunkedReader returns data in chunks of the given sizes.
+type chunkedReader struct {
+	chunks [][]byte
+}
+
+func (r *chunkedReader) Read(b []byte) (int, error) {
+	for len(r.chunks) > 0 {
+		n := copy(b, r.chunks[0])
+		r.chunks[0] = r.chunks[0][n:]
+		if len(r.chunks[0]) == 0 {
+			r.chunks = r.chunks[1:]
+		}
+		if n > 0 || len(b) == 0 {
+			return n, nil
+		}
+	}
+
+	return 0, io.EOF
+}
+
+func newTestResponseWriter(t testing.TB) (gin.ResponseWriter, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Writer.Header().Set("Content-Type", "text/plain")
+	return c.Writer, recorder
+}
+
+func checkBodyWriter(t testing.TB, data []byte, sizes []byte, maxSize int, tailSize int) {
+	t.Helper()
+
+	writer, recorder := newTestResponseWriter(t)
+	bw := newBodyWriter(writer, maxSize, tailSize, true, nil, false)
+	defer bw.release()
+
+	for i, chunk := range chunks(data, sizes) {
+		var n int
+		var err error
+		switch i % 3 {
+		case 0:
+			n, err = bw.Write(chunk)
+		case 1:
+			n, err = bw.WriteString(string(chunk))
+		default:
+			var n64 int64
+			n64, err = bw.ReadFrom(bytes.NewReader(chunk))
+			n = int(n64)
+		}
+
+		if err != nil || n != len(chunk) {
+			t.Fatalf("write chunk %d: n=%d err=%v, expected n=%d", i, n, err, len(chunk))
+		}
+	}
+
+	if !bytes.Equal(recorder.Body.Bytes(), data) {
+		t.Fatalf("client received %q, expected %q", recorder.Body.Bytes(), data)
+	}
+
+	if bw.length() != len(data) {
+		t.Fatalf("bytes=%d, expected %d", bw.length(), len(data))
+	}
+
+	captured := bw.captured()
+	if expected := data[:min(max(maxSize, 0), len(data))]; !bytes.Equal(captured.body, expected) {
+		t.Fatalf("captured %q, expected %q", captured.body, expected)
+	}
+
+	if tailSize > 0 {
+		if expected := data[max(len(data)-tailSize, 0):]; !bytes.Equal(captured.tail, expected) {
+			t.Fatalf("tail %q, expected %q", captured.tail, expected)
+		}
+	}
+}
+
+func checkBodyReader(t testing.TB, data []byte, sizes []byte, maxSize int, tailSize int) {
+	t.Helper()
+
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain")
+
+	source := io.NopCloser(&chunkedReader{chunks: chunks(append([]byte{}, data...), sizes)})
+	br := newBodyReader(source, maxSize, tailSize, true, header, false, nil, false)
+	defer br.release()
+
+	read, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if !bytes.Equal(read, data) {
+		t.Fatalf("handler read %q, expected %q", read, data)
+	}
+
+	if br.length() != len(data) {
+		t.Fatalf("bytes=%d, expected %d", br.length(), len(data))
+	}
+
+	captured := br.captured()
+	if expected := data[:min(max(maxSize, 0), len(data))]; !bytes.Equal(captured.body, expected) {
+		t.Fatalf("captured %q, expected %q", captured.body, expected)
+	}
+
+	if tailSize > 0 {
+		if expected := data[max(len(data)-tailSize, 0):]; !bytes.Equal(captured.tail, expected) {
+			t.Fatalf("tail %q, expected %q", captured.tail, expected)
+		}
+	}
+}
+
+func TestBodyWriterTruncation(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(strings.Repeat("0123456789", 10))
+
+	tests := map[string]struct {
+		sizes   []byte
+		maxSize int
+	}{
+		"single write under limit":        {sizes: []byte{100}, maxSize: 200},
+		"single write over limit":         {sizes: []byte{100}, maxSize: 64},
+		"write crossing limit":            {sizes: []byte{60, 40}, maxSize: 64},
+		"small writes after large buffer": {sizes: []byte{63, 1, 1, 35}, maxSize: 64},
+		"exact limit":                     {sizes: []byte{64, 36}, maxSize: 64},
+		"one byte writes":                 {sizes: []byte{1}, maxSize: 10},
+		"zero limit":                      {sizes: []byte{7}, maxSize: 0},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			checkBodyWriter(t, data, tt.sizes, tt.maxSize, 0)
+			checkBodyReader(t, data, tt.sizes, tt.maxSize, 0)
+		})
+	}
+}
+
+func TestBodyWriterShortWrite(t *testing.T) {
+	t.Parallel()
+
+	writer, _ := newTestResponseWriter(t)
+	bw := newBodyWriter(&shortResponseWriter{ResponseWriter: writer, limit: 5}, 64, 0, true, nil, false)
+	defer bw.release()
+
+	n, err := bw.Write([]byte("hello world"))
+	if err != io.ErrShortWrite || n != 5 {
+		t.Fatalf("n=%d err=%v", n, err)
+	}
+
+	if got := string(bw.captured().body); got != "hello" || bw.length() != 5 {
+		t.Fatalf("captured %q (%d bytes), expected what the client received", got, bw.length())
+	}
+
+	_, _ = bw.Write([]byte("!"))
+	if bw.err != io.ErrShortWrite {
+		t.Fatalf("err=%v, expected the first write error", bw.err)
+	}
+}
+
+type shortResponseWriter struct {
+	gin.ResponseWriter
+	limit int
+}
+
+func (w *shortResponseWriter) Write(b []byte) (int, error) {
+	if len(b) > w.limit {
+		n, _ := w.ResponseWriter.Write(b[:w.limit])
+		return n, io.ErrShortWrite
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func FuzzBodyWriter(f *testing.F) {
+	f.Add([]byte("hello world"), []byte{3}, 5, 0)
+	f.Add([]byte(strings.Repeat("a", 200)), []byte{60, 1, 200}, 64, 16)
+	f.Add([]byte("abc"), []byte{0, 1}, 64, 2)
+	f.Add([]byte{}, []byte{}, 0, 0)
+
+	f.Fuzz(func(t *testing.T, data []byte, sizes []byte, maxSize int, tailSize int) {
+		checkBodyWriter(t, data, sizes, maxSize%(1<<16), max(tailSize%256, 0))
+	})
+}
+
+func FuzzBodyReader(f *testing.F) {
+	f.Add([]byte("hello world"), []byte{3}, 5, 0)
+	f.Add([]byte(strings.Repeat("a", 200)), []byte{60, 1, 200}, 64, 16)
+	f.Add([]byte("abc"), []byte{0, 1}, 64, 2)
+	f.Add([]byte{}, []byte{}, 0, 0)
+
+	f.Fuzz(func(t *testing.T, data []byte, sizes []byte, maxSize int, tailSize int) {
+		checkBodyReader(t, data, sizes, maxSize%(1<<16), max(tailSize%256, 0))
+	})
+}
+
+// writerFeatures reports what a handler can do with its response writer.
+func writerFeatures(c *gin.Context) map[string]any {
+	rc := http.NewResponseController(c.Writer)
+	deadline := time.Now().Add(time.Minute)
+
+	_, isHijacker := c.Writer.(http.Hijacker)
+	_, isFlusher := c.Writer.(http.Flusher)
+	_, isStringWriter := c.Writer.(io.StringWriter)
+
+	features := map[string]any{
+		"set_write_deadline": rc.SetWriteDeadline(deadline),
+		"set_read_deadline":  rc.SetReadDeadline(deadline),
+		"enable_full_duplex": rc.EnableFullDuplex(),
+		"hijacker":           isHijacker,
+		"flusher":            isFlusher,
+		"string_writer":      isStringWriter,
+		"pusher":             c.Writer.Pusher() != nil,
+		"close_notify":       c.Writer.CloseNotify() != nil,
+		"written_before":     c.Writer.Written(),
+	}
+
+	c.Writer.WriteHeader(http.StatusAccepted)
+	c.Writer.WriteHeaderNow()
+	features["written_after_header"] = c.Writer.Written()
+
+	_, _ = c.Writer.WriteString("hello")
+	features["flush"] = rc.Flush()
+	features["status"] = c.Writer.Status()
+	features["size"] = c.Writer.Size()
+
+	return features
+}
+
+func serveWriterFeatures(t *testing.T, middlewares ...gin.HandlerFunc) (map[string]any, string) {
+	t.Helper()
+
+	var features map[string]any
+	var hijacked string
+
+	router := gin.New()
+	router.Use(middlewares...)
+	router.GET("/features", func(c *gin.Context) {
+		features = writerFeatures(c)
+	})
+	router.GET("/hijack", func(c *gin.Context) {
+		conn, buf, err := c.Writer.Hijack()
+		if err != nil {
+			hijacked = err.Error()
+			return
+		}
+		defer conn.Close()
+
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		_ = buf.Flush()
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, path := range []string{"/features", "/hijack"} {
+		res, err := server.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if path == "/hijack" && hijacked == "" {
+			hijacked = string(body)
+		}
+	}
+
+	return features, hijacked
+}
+
+func TestBodyWriterPassthrough(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config := Config{
+		WithRequestBody:  true,
+		WithResponseBody: true,
+		WithBodyHash:     true,
+	}
+
+	expectedFeatures, expectedHijack := serveWriterFeatures(t)
+	features, hijacked := serveWriterFeatures(t, NewWithConfig(logger, config))
+
+	if expectedFeatures["set_write_deadline"] != nil || expectedFeatures["enable_full_duplex"] != nil {
+		t.Fatalf("unwrapped writer does not support deadlines: %v", expectedFeatures)
+	}
+
+	for name, expected := range expectedFeatures {
+		if features[name] != expected {
+			t.Errorf("%s: got %v, expected %v", name, features[name], expected)
+		}
+	}
+
+	if hijacked != expectedHijack || hijacked != "hijacked" {
+		t.Errorf("hijack: got %q, expected %q", hijacked, expectedHijack)
+	}
+}
+
+func TestBodyReaderError(t *testing.T) {
+	t.Parallel()
+
+	writer, _ := newTestResponseWriter(t)
+	source := http.MaxBytesReader(writer, io.NopCloser(strings.NewReader("hello world")), 5)
+	br := newBodyReader(source, 64, 0, true, http.Header{}, false, nil, false)
+	defer br.release()
+
+	if _, err := io.ReadAll(br); err == nil {
+		t.Fatal("expected the body to be rejected")
+	}
+
+	attrs := map[string]string{}
+	for _, attr := range bodyErrorAttrs(br.err) {
+		attrs[attr.Key] = attr.Value.String()
+	}
+
+	if attrs["body_error"] != "http: request body too large" || attrs["body_too_large"] != "true" || attrs["body_limit"] != "5" {
+		t.Fatalf("unexpected attributes: %v", attrs)
+	}
+
+	if attrs := bodyErrorAttrs(nil); len(attrs) != 0 {
+		t.Fatalf("unexpected attributes without error: %v", attrs)
+	}
+}
+
+func TestBodyWriterStreamingMetrics(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	writer, _ := newTestResponseWriter(t)
+	bw := newBodyWriter(writer, 64, 0, false, nil, false)
+	defer bw.release()
+
+	bw.WriteHeaderNow()
+	_, _ = bw.WriteString("data: a\n\n")
+	bw.Flush()
+	_, _ = bw.Write([]byte("data: b\n\n"))
+	bw.Flush()
+
+	attrs := map[string]slog.Value{}
+	for _, attr := range streamingAttrs(bw, start) {
+		attrs[attr.Key] = attr.Value
+	}
+
+	if attrs["writes"].Int64() != 2 || attrs["flushes"].Int64() != 2 {
+		t.Fatalf("unexpected counters: %v", attrs)
+	}
+
+	if ttfb, ok := attrs["time_to_first_byte"]; !ok || ttfb.Duration() < 0 || ttfb.Duration() > attrs["last_write"].Time().Sub(start) {
+		t.Fatalf("unexpected timings: %v", attrs)
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		bytes    int
+		duration time.Duration
+		expected int64
+		ok       bool
+	}{
+		"one megabyte per second": {bytes: 1024 * 1024, duration: time.Second, expected: 1024 * 1024, ok: true},
+		"half a second":           {bytes: 100, duration: 500 * time.Millisecond, expected: 200, ok: true},
+		"empty body":              {bytes: 0, duration: time.Second},
+		"unknown duration":        {bytes: 100, duration: 0},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if bps, ok := throughput(tt.bytes, tt.duration); bps != tt.expected || ok != tt.ok {
+				t.Fatalf("got %d %v, expected %d %v", bps, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package sloggin
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strings"
+)
+
+// BodyDecoder returns a reader decoding a body compressed with a Content-Encoding.
+type BodyDecoder func(r io.Reader) (io.Reader, error)
+
+// BodyDecoders are the supported Content-Encoding, keyed by lowercase name.
+// Brotli and zstd are available with the `brotli` and `zstd` build tags.
+var BodyDecoders = map[string]BodyDecoder{
+	"gzip":    decodeGzip,
+	"x-gzip":  decodeGzip,
+	"deflate": decodeDeflate,
+}
+
+func decodeGzip(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// decodeDeflate accepts both zlib-wrapped (RFC 1950) and raw (RFC 1951) deflate streams.
+func decodeDeflate(r io.Reader) (io.Reader, error) {
+	buf, ok := r.(*bytes.Reader)
+	if !ok {
+		return zlib.NewReader(r)
+	}
+
+	zr, err := zlib.NewReader(buf)
+	if err == nil {
+		return zr, nil
+	}
+
+	_, _ = buf.Seek(0, io.SeekStart)
+	return flate.NewReader(buf), nil
+}
+
+// contentEncodings splits a Content-Encoding header, in the order they were applied.
+func contentEncodings(contentEncoding string) []string {
+	encodings := []string{}
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding != "" && encoding != "identity" {
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	return encodings
+}
+
+// isSupportedContentEncoding returns true when every encoding has a decoder.
+func isSupportedContentEncoding(contentEncoding string) bool {
+	for _, encoding := range contentEncodings(contentEncoding) {
+		if _, found := BodyDecoders[encoding]; !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeBody decompresses a captured body, up to DecompressedBodyMaxSize bytes.
+// Partially captured bodies are decoded as far as possible. The returned
+// boolean is true when the decoded body is incomplete.
+func decodeBody(body []byte, contentEncoding string) ([]byte, bool, error) {
+	encodings := contentEncodings(contentEncoding)
+	incomplete := false
+
+	// encodings are listed in the order they were applied
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoder, found := BodyDecoders[encodings[i]]
+		if !found {
+			return nil, false, errors.New("sloggin: unsupported content encoding: " + encodings[i])
+		}
+
+		r, err := decoder(bytes.NewReader(body))
+		if err != nil {
+			return nil, false, err
+		}
+
+		decoded, err := io.ReadAll(io.LimitReader(r, int64(DecompressedBodyMaxSize)+1))
+		if closer, ok := r.(io.Closer); ok {
+			_ = closer.Close()
+		}
+
+		if len(decoded) > DecompressedBodyMaxSize {
+			decoded = decoded[:DecompressedBodyMaxSize]
+			incomplete = true
+		} else if err != nil {
+			if !errors.Is(err, io.ErrUnexpectedEOF) || len(decoded) == 0 {
+				return nil, false, err
+			}
+			incomplete = true
+		}
+
+		body = decoded
+	}
+
+	return body, incomplete, nil
+}
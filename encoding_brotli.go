@@ -0,0 +1,15 @@
+//go:build brotli
+
+package sloggin
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+func init() {
+	BodyDecoders["br"] = func(r io.Reader) (io.Reader, error) {
+		return brotli.NewReader(r), nil
+	}
+}
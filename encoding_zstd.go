@@ -0,0 +1,28 @@
+//go:build zstd
+
+package sloggin
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func init() {
+	BodyDecoders["zstd"] = func(r io.Reader) (io.Reader, error) {
+		// The history window is allocated from the frame header, before any
+		// output: a tiny frame may declare a huge window.
+		limit := uint64(max(DecompressedBodyMaxSize, zstd.MinWindowSize))
+
+		decoder, err := zstd.NewReader(r,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxWindow(limit),
+			zstd.WithDecoderMaxMemory(2*limit),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	}
+}
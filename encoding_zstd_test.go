@@ -0,0 +1,60 @@
+//go:build zstd
+
+package sloggin
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// not parallel: measures allocations
+func TestDecodeZstdWindowLimit(t *testing.T) {
+	// frame header declaring a 512MB window, followed by an empty last raw block
+	frame := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 19 << 3, 0x01, 0x00, 0x00}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	_, _, err := decodeBody(frame, "zstd")
+
+	runtime.ReadMemStats(&after)
+	if err == nil {
+		t.Fatal("expected the window size to be rejected")
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16*1024*1024 {
+		t.Fatalf("allocated %d bytes", allocated)
+	}
+}
+
+func TestDecodeZstd(t *testing.T) {
+	t.Parallel()
+
+	plain := strings.Repeat("hello world ", 300_000) // larger than DecompressedBodyMaxSize
+
+	var compressed bytes.Buffer
+	zw, _ := zstd.NewWriter(&compressed, zstd.WithWindowSize(1<<20))
+	_, _ = zw.Write([]byte(plain))
+	_ = zw.Close()
+
+	decoded, incomplete, err := decodeBody(compressed.Bytes(), "zstd")
+	if err != nil || !incomplete || string(decoded) != plain[:DecompressedBodyMaxSize] {
+		t.Fatalf("got %d bytes, incomplete=%v, err=%v", len(decoded), incomplete, err)
+	}
+
+	// within the limit
+	small := []byte(plain[:1000])
+	compressed.Reset()
+	zw.Reset(&compressed)
+	_, _ = zw.Write(small)
+	_ = zw.Close()
+
+	decoded, incomplete, err = decodeBody(compressed.Bytes(), "zstd")
+	if err != nil || incomplete || !bytes.Equal(decoded, small) {
+		t.Fatalf("got %q, incomplete=%v, err=%v", decoded, incomplete, err)
+	}
+}
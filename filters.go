@@ -88,6 +88,19 @@ func IgnoreStatusLessThanOrEqual(status int) Filter {
 	return AcceptStatusGreaterThan(status)
 }
 
+// Error
+func AcceptError() Filter {
+	return func(c *gin.Context) bool {
+		return c.Writer.Status() >= 400 || len(c.Errors) > 0
+	}
+}
+
+func IgnoreError() Filter {
+	return func(c *gin.Context) bool {
+		return c.Writer.Status() < 400 && len(c.Errors) == 0
+	}
+}
+
 // Path
 func AcceptPath(urls ...string) Filter {
 	return func(c *gin.Context) bool {
@@ -0,0 +1,249 @@
+package sloggin
+
+import (
+	"bufio"
+	"bytes"
+	"log/slog"
+	"mime"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maximum size of the headers of a multipart part
+const multipartMaxHeaderSize = 8 * 1024
+
+type multipartState int
+
+const (
+	multipartPreamble multipartState = iota
+	multipartDelimiter
+	multipartHeaders
+	multipartContent
+	multipartEpilogue
+)
+
+type multipartField struct {
+	name  string
+	value []byte
+}
+
+type multipartFile struct {
+	field       string
+	filename    string
+	contentType string
+	size        int
+}
+
+// multipartSummary parses a multipart/form-data body on the fly, while the
+// handler reads it. It keeps text field values and file metadata, but never
+// buffers file contents.
+type multipartSummary struct {
+	delimiter []byte // "\r\n--" + boundary
+	state     multipartState
+	pending   []byte
+
+	fields []multipartField
+	files  []multipartFile
+
+	// current part
+	field *multipartField
+	file  *multipartFile
+}
+
+func newMultipartSummary(boundary string) *multipartSummary {
+	return &multipartSummary{
+		delimiter: []byte("\r\n--" + boundary),
+		state:     multipartPreamble,
+		// The first delimiter is not preceded by a line break.
+		pending: []byte("\r\n"),
+	}
+}
+
+// implements io.Writer
+func (s *multipartSummary) Write(b []byte) (int, error) {
+	s.pending = append(s.pending, b...)
+
+	for {
+		switch s.state {
+		case multipartPreamble, multipartContent:
+			i := bytes.Index(s.pending, s.delimiter)
+			if i < 0 {
+				// keep enough bytes to detect a delimiter split across writes
+				keep := min(len(s.pending), len(s.delimiter)-1)
+				s.consume(s.pending[:len(s.pending)-keep])
+				s.pending = s.pending[len(s.pending)-keep:]
+				return len(b), nil
+			}
+
+			s.consume(s.pending[:i])
+			s.endPart()
+			s.pending = s.pending[i+len(s.delimiter):]
+			s.state = multipartDelimiter
+
+		case multipartDelimiter:
+			i := bytes.Index(s.pending, []byte("\r\n"))
+			if i < 0 {
+				if bytes.HasPrefix(s.pending, []byte("--")) {
+					s.state = multipartEpilogue
+				}
+				return len(b), nil
+			}
+
+			if bytes.HasPrefix(s.pending, []byte("--")) {
+				s.state = multipartEpilogue
+				continue
+			}
+
+			s.pending = s.pending[i+2:]
+			s.state = multipartHeaders
+
+		case multipartHeaders:
+			i := bytes.Index(s.pending, []byte("\r\n\r\n"))
+			if i < 0 {
+				if len(s.pending) > multipartMaxHeaderSize {
+					s.state = multipartEpilogue
+				}
+				return len(b), nil
+			}
+
+			s.startPart(s.pending[:i+4])
+			s.pending = s.pending[i+4:]
+			s.state = multipartContent
+
+		case multipartEpilogue:
+			s.pending = nil
+			return len(b), nil
+		}
+	}
+}
+
+func (s *multipartSummary) startPart(rawHeader []byte) {
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(rawHeader))).ReadMIMEHeader()
+	if err != nil {
+		return
+	}
+
+	_, params, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if err != nil {
+		return
+	}
+
+	if filename, ok := params["filename"]; ok {
+		s.file = &multipartFile{
+			field:       params["name"],
+			filename:    filename,
+			contentType: header.Get("Content-Type"),
+		}
+		return
+	}
+
+	s.field = &multipartField{name: params["name"]}
+}
+
+func (s *multipartSummary) consume(b []byte) {
+	if s.state != multipartContent {
+		return
+	}
+
+	if s.file != nil {
+		s.file.size += len(b)
+	} else if s.field != nil && len(s.field.value) <= FormFieldValueMaxLength {
+		s.field.value = append(s.field.value, b[:min(len(b), FormFieldValueMaxLength+1-len(s.field.value))]...)
+	}
+}
+
+func (s *multipartSummary) endPart() {
+	if s.file != nil {
+		s.files = append(s.files, *s.file)
+	} else if s.field != nil {
+		s.fields = append(s.fields, *s.field)
+	}
+
+	s.file = nil
+	s.field = nil
+}
+
+// attrs returns the summary of the parts parsed so far. A part interrupted by
+// the end of the body (eg: when the handler did not read it entirely) is included.
+func (s *multipartSummary) attrs() []slog.Attr {
+	fields := url.Values{}
+	for _, field := range s.fields {
+		fields.Add(field.name, string(field.value))
+	}
+	if s.field != nil {
+		fields.Add(s.field.name, string(s.field.value))
+	}
+
+	files := s.files
+	if s.file != nil {
+		files = append(files, *s.file)
+	}
+
+	list := make([]map[string]any, 0, len(files))
+	for _, file := range files {
+		list = append(list, map[string]any{
+			"field":        file.field,
+			"filename":     file.filename,
+			"content_type": file.contentType,
+			"size":         file.size,
+		})
+	}
+
+	return []slog.Attr{
+		slog.Group("form",
+			slog.Group("fields", extractFormFields(fields)...),
+			slog.Any("files", list),
+		),
+	}
+}
+
+// extractFormFields turns form fields into slog attributes, redacting
+// HiddenBodyFields and applying FormFieldValueMaxLength.
+func extractFormFields(values url.Values) []any {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kv := make([]any, 0, len(keys))
+	for _, k := range keys {
+		_, hidden := HiddenBodyFields[strings.ToLower(k)]
+
+		v := make([]string, 0, len(values[k]))
+		for _, value := range values[k] {
+			if hidden {
+				v = append(v, RedactedValue)
+			} else {
+				v = append(v, truncateString(value, FormFieldValueMaxLength))
+			}
+		}
+
+		kv = append(kv, slog.Any(k, v))
+	}
+
+	return kv
+}
+
+// formAttributes decodes an application/x-www-form-urlencoded body.
+// A truncated body may end with a partial value.
+func formAttributes(body []byte) []slog.Attr {
+	values, _ := url.ParseQuery(string(body))
+	return []slog.Attr{
+		slog.Group("form",
+			slog.Group("fields", extractFormFields(values)...),
+		),
+	}
+}
+
+// multipartBoundary returns the boundary of a multipart/form-data content type.
+func multipartBoundary(contentType string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", false
+	}
+
+	return params["boundary"], true
+}
@@ -0,0 +1,80 @@
+package sloggin
+
+import (
+	"bytes"
+	"log/slog"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+func newTestMultipartBody(t *testing.T) ([]byte, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("user", "bob")
+	_ = w.WriteField("password", "hunter2")
+
+	file, err := w.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// content looking like a delimiter
+	_, _ = file.Write([]byte("\r\n--" + w.Boundary()[:10] + strings.Repeat("x", 990)))
+	_ = w.WriteField("comment", "hello")
+	_ = w.Close()
+
+	return body.Bytes(), w.Boundary()
+}
+
+func multipartSummaryString(s *multipartSummary) string {
+	return slog.GroupValue(s.attrs()...).String()
+}
+
+func TestMultipartSummary(t *testing.T) {
+	t.Parallel()
+
+	body, boundary := newTestMultipartBody(t)
+
+	whole := newMultipartSummary(boundary)
+	_, _ = whole.Write(body)
+
+	expected := "[form=[fields=[comment=[hello] password=[" + RedactedValue + "] user=[bob]] files=[map[content_type:application/octet-stream field:avatar filename:avatar.png size:1004]]]]"
+	if got := multipartSummaryString(whole); got != expected {
+		t.Fatalf("got %s, expected %s", got, expected)
+	}
+
+	// a stream split into 1-byte writes
+	split := newMultipartSummary(boundary)
+	for i := range body {
+		_, _ = split.Write(body[i : i+1])
+	}
+	if got := multipartSummaryString(split); got != expected {
+		t.Fatalf("1-byte writes: got %s, expected %s", got, expected)
+	}
+
+	// interrupted in the file
+	partial := newMultipartSummary(boundary)
+	_, _ = partial.Write(body[:bytes.Index(body, []byte("xxxx"))+100])
+	got := multipartSummaryString(partial)
+	if !strings.Contains(got, "filename:avatar.png") || strings.Contains(got, "comment") || strings.Contains(got, "hunter2") {
+		t.Fatalf("unexpected summary of a partial body: %s", got)
+	}
+}
+
+func TestFormAttributes(t *testing.T) {
+	t.Parallel()
+
+	got := slog.GroupValue(formAttributes([]byte("user=bob&Password=hunter2&tags=a&tags=b&note=" + strings.Repeat("x", 300)))...).String()
+	expected := "[form=[fields=[Password=[" + RedactedValue + "] note=[" + strings.Repeat("x", 256) + "...] tags=[a b] user=[bob]]]]"
+	if got != expected {
+		t.Fatalf("got %s, expected %s", got, expected)
+	}
+
+	// truncated body
+	got = slog.GroupValue(formAttributes([]byte("user=bob&password=hun"))...).String()
+	if got != "[form=[fields=[password=["+RedactedValue+"] user=[bob]]]]" {
+		t.Fatalf("unexpected fields of a truncated body: %s", got)
+	}
+}
@@ -0,0 +1,49 @@
+package sloggin
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// extractHeaders turns headers into slog attributes.
+//
+// When allowed is not empty, only the listed headers are logged. Masked headers
+// are kept with their values replaced, and take precedence over hidden ones,
+// which are dropped.
+func extractHeaders(header http.Header, hidden map[string]struct{}, allowed map[string]struct{}, masked map[string]Masker) []any {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kv := make([]any, 0, len(keys))
+	for _, k := range keys {
+		lower := strings.ToLower(k)
+
+		if len(allowed) > 0 {
+			if _, found := allowed[lower]; !found {
+				continue
+			}
+		}
+
+		if mask, found := masked[lower]; found && mask != nil {
+			values := make([]string, 0, len(header[k]))
+			for _, v := range header[k] {
+				values = append(values, mask(v))
+			}
+			kv = append(kv, slog.Any(k, values))
+			continue
+		}
+
+		if _, found := hidden[lower]; found {
+			continue
+		}
+
+		kv = append(kv, slog.Any(k, header[k]))
+	}
+
+	return kv
+}
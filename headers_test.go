@@ -0,0 +1,67 @@
+package sloggin
+
+import (
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestExtractHeaders(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{
+		"Authorization": {"Bearer abcdefgh1234"},
+		"Content-Type":  {"application/json"},
+		"Cookie":        {"a=b"},
+		"X-Api-Key":     {"0123456789f00d"},
+		"X-Request-Id":  {"42"},
+	}
+	hidden := map[string]struct{}{"authorization": {}, "cookie": {}, "x-api-key": {}}
+
+	tests := map[string]struct {
+		allowed  map[string]struct{}
+		masked   map[string]Masker
+		expected string
+	}{
+		"hidden": {
+			expected: "[Content-Type=[application/json] X-Request-Id=[42]]",
+		},
+		"masked over hidden": {
+			masked:   map[string]Masker{"authorization": MaskRedact, "x-api-key": MaskLast4},
+			expected: "[Authorization=[[REDACTED]] Content-Type=[application/json] X-Api-Key=[****f00d] X-Request-Id=[42]]",
+		},
+		"allowed": {
+			allowed:  map[string]struct{}{"content-type": {}, "authorization": {}},
+			expected: "[Content-Type=[application/json]]",
+		},
+		"allowed and masked": {
+			allowed:  map[string]struct{}{"authorization": {}},
+			masked:   map[string]Masker{"authorization": MaskLast4},
+			expected: "[Authorization=[****1234]]",
+		},
+		"nil masker": {
+			masked:   map[string]Masker{"authorization": nil},
+			expected: "[Content-Type=[application/json] X-Request-Id=[42]]",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := slog.GroupValue(attrsFromArgs(extractHeaders(header, hidden, tt.allowed, tt.masked))...).String()
+			if got != tt.expected {
+				t.Fatalf("got %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func attrsFromArgs(args []any) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(args))
+	for _, arg := range args {
+		attrs = append(attrs, arg.(slog.Attr))
+	}
+	return attrs
+}
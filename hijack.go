@@ -0,0 +1,136 @@
+package sloggin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ net.Conn = (*hijackedConn)(nil)
+
+// hijackedConn counts bytes exchanged on a hijacked connection (eg: WebSocket),
+// and reports its end when it is closed.
+type hijackedConn struct {
+	net.Conn
+	start   time.Time
+	read    atomic.Int64
+	written atomic.Int64
+	onClose func(*hijackedConn)
+	once    sync.Once
+
+	mu  sync.Mutex
+	err error // first read or write error
+}
+
+// wrapHijackedConn wraps a hijacked connection and its buffers, so that bytes
+// read from the buffered reader are counted too.
+func wrapHijackedConn(conn net.Conn, rw *bufio.ReadWriter, onClose func(*hijackedConn)) (net.Conn, *bufio.ReadWriter) {
+	wrapped := &hijackedConn{
+		Conn:    conn,
+		start:   time.Now(),
+		onClose: onClose,
+	}
+
+	if rw != nil {
+		rw = bufio.NewReadWriter(
+			bufio.NewReader(&hijackedReader{reader: rw.Reader, conn: wrapped}),
+			bufio.NewWriter(wrapped),
+		)
+	}
+
+	return wrapped, rw
+}
+
+// implements net.Conn
+func (c *hijackedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read.Add(int64(n))
+	c.recordError(err)
+	return n, err
+}
+
+// implements net.Conn
+func (c *hijackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written.Add(int64(n))
+	c.recordError(err)
+	return n, err
+}
+
+// implements net.Conn
+func (c *hijackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		if c.onClose != nil {
+			c.onClose(c)
+		}
+	})
+	return err
+}
+
+func (c *hijackedConn) recordError(err error) {
+	if err == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// reason explains why the connection was closed.
+func (c *hijackedConn) reason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.err == nil:
+		return "closed by server"
+	case errors.Is(c.err, io.EOF):
+		return "closed by client"
+	default:
+		return c.err.Error()
+	}
+}
+
+// hijackedReader counts bytes read from the buffered reader of a hijacked
+// connection, which reads from the connection underneath.
+type hijackedReader struct {
+	reader io.Reader
+	conn   *hijackedConn
+}
+
+// implements io.Reader
+func (r *hijackedReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.conn.read.Add(int64(n))
+	r.conn.recordError(err)
+	return n, err
+}
+
+// logHijackedConnClosed logs the end of a hijacked connection.
+func logHijackedConnClosed(ctx context.Context, logger *slog.Logger, level slog.Level, conn *hijackedConn, request slog.Attr, base []slog.Attr) {
+	attributes := append(
+		[]slog.Attr{
+			request,
+			slog.Group("connection",
+				slog.Duration("duration", time.Since(conn.start)),
+				slog.Int64("bytes_read", conn.read.Load()),
+				slog.Int64("bytes_written", conn.written.Load()),
+				slog.String("reason", conn.reason()),
+			),
+		},
+		base...,
+	)
+
+	logger.LogAttrs(ctx, level, "Connection closed", attributes...)
+}
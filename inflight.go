@@ -0,0 +1,112 @@
+package sloggin
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InflightRequest describes a request being executed.
+type InflightRequest struct {
+	ID           string    `json:"id"`
+	Method       string    `json:"method"`
+	Route        string    `json:"route"`
+	Path         string    `json:"path"`
+	ClientIP     string    `json:"client_ip"`
+	Start        time.Time `json:"start"`
+	Elapsed      string    `json:"elapsed"`
+	BytesRead    int       `json:"bytes_read"`
+	BytesWritten int       `json:"bytes_written"`
+}
+
+type inflightRequest struct {
+	id       string
+	method   string
+	route    string
+	path     string
+	clientIP string
+	start    time.Time
+	reader   *bodyReader
+	writer   *bodyWriter
+}
+
+// InflightRegistry keeps track of the requests being executed, to find out
+// which ones are stuck (see Config.InflightRegistry).
+type InflightRegistry struct {
+	mu       sync.Mutex
+	next     uint64
+	requests map[uint64]*inflightRequest
+}
+
+// NewInflightRegistry returns an empty registry of in-flight requests.
+func NewInflightRegistry() *InflightRegistry {
+	return &InflightRegistry{
+		requests: map[uint64]*inflightRequest{},
+	}
+}
+
+// add registers a request, until the returned function is called.
+func (r *InflightRegistry) add(req *inflightRequest) func() {
+	if r == nil {
+		return func() {}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.next
+	r.next++
+	r.requests[key] = req
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		delete(r.requests, key)
+	}
+}
+
+// Requests returns the requests being executed, oldest first.
+func (r *InflightRegistry) Requests() []InflightRequest {
+	r.mu.Lock()
+	requests := make([]*inflightRequest, 0, len(r.requests))
+	for _, req := range r.requests {
+		requests = append(requests, req)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].start.Before(requests[j].start)
+	})
+
+	now := time.Now()
+	list := make([]InflightRequest, 0, len(requests))
+	for _, req := range requests {
+		list = append(list, InflightRequest{
+			ID:           req.id,
+			Method:       req.method,
+			Route:        req.route,
+			Path:         req.path,
+			ClientIP:     req.clientIP,
+			Start:        req.start.UTC(),
+			Elapsed:      now.Sub(req.start).String(),
+			BytesRead:    req.reader.length(),
+			BytesWritten: req.writer.length(),
+		})
+	}
+
+	return list
+}
+
+// Handler returns a gin.HandlerFunc listing the requests being executed, as
+// JSON. Eg: router.GET("/debug/requests/inflight", registry.Handler()).
+func (r *InflightRegistry) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"requests": r.Requests(),
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package sloggin
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInflightRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := NewInflightRegistry()
+	config := DefaultConfig()
+	config.InflightRegistry = registry
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	router := gin.New()
+	router.Use(NewWithConfig(slog.New(slog.NewTextHandler(io.Discard, nil)), config))
+	router.POST("/upload/:id", func(c *gin.Context) {
+		_, _ = io.Copy(io.Discard, c.Request.Body)
+		_, _ = c.Writer.WriteString("ok")
+		c.Writer.Flush()
+		close(started)
+		<-release
+	})
+
+	debug := gin.New()
+	debug.GET("/debug/requests/inflight", registry.Handler())
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/upload/42", strings.NewReader("hello"))
+		req.Header.Set(RequestIDHeaderKey, "req-42")
+		res, err := server.Client().Do(req)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+	}()
+
+	<-started
+
+	rec := httptest.NewRecorder()
+	debug.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/requests/inflight", nil))
+
+	var body struct {
+		Requests []InflightRequest `json:"requests"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%v: %s", err, rec.Body.String())
+	}
+
+	if len(body.Requests) != 1 {
+		t.Fatalf("expected one request in flight: %s", rec.Body.String())
+	}
+
+	req := body.Requests[0]
+	if req.ID != "req-42" || req.Method != http.MethodPost || req.Route != "/upload/:id" || req.Path != "/upload/42" || req.ClientIP != "127.0.0.1" || req.BytesRead != 5 || req.BytesWritten != 2 || req.Start.IsZero() || req.Elapsed == "" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+
+	close(release)
+	<-done
+
+	if requests := registry.Requests(); len(requests) != 0 {
+		t.Fatalf("requests still in flight: %+v", requests)
+	}
+}
@@ -0,0 +1,506 @@
+package sloggin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// jsonPathElement is a step in the location of a value inside a JSON document:
+// either an object key or an array index.
+type jsonPathElement struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// jsonPathSegment is a compiled step of a JSON path such as `$.user.password`,
+// `$..token` or `$.items[*].secret`.
+type jsonPathSegment struct {
+	name      string
+	index     int
+	isIndex   bool
+	wildcard  bool
+	recursive bool
+}
+
+func (s jsonPathSegment) match(elem jsonPathElement) bool {
+	if s.wildcard {
+		return true
+	}
+
+	if s.isIndex {
+		return elem.isIndex && elem.index == s.index
+	}
+
+	return !elem.isIndex && elem.key == s.name
+}
+
+// compileJSONPath parses the subset of JSONPath supported for body redaction:
+// `$`, `.name`, `..name`, `.*`, `[*]`, `[0]` and `['name']`.
+func compileJSONPath(path string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.New("sloggin: json path must start with '$': " + path)
+	}
+
+	segments := []jsonPathSegment{}
+	rest := path[1:]
+
+	for len(rest) > 0 {
+		segment := jsonPathSegment{}
+
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segment.recursive = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.New("sloggin: unterminated '[' in json path: " + path)
+			}
+
+			inner := rest[1:end]
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				segment.wildcard = true
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segment.name = inner[1 : len(inner)-1]
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, errors.New("sloggin: invalid index in json path: " + path)
+				}
+				segment.index = index
+				segment.isIndex = true
+			}
+
+			segments = append(segments, segment)
+			continue
+		default:
+			return nil, errors.New("sloggin: unexpected character in json path: " + path)
+		}
+
+		// bracket notation after `..`, eg: `$..[0]`
+		if segment.recursive && strings.HasPrefix(rest, "[") {
+			next, err := compileJSONPath("$" + rest)
+			if err != nil {
+				return nil, err
+			}
+			if len(next) > 0 {
+				next[0].recursive = true
+			}
+			return append(segments, next...), nil
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+
+		name := rest[:end]
+		rest = rest[end:]
+		if name == "" {
+			return nil, errors.New("sloggin: empty name in json path: " + path)
+		}
+
+		if name == "*" {
+			segment.wildcard = true
+		} else {
+			segment.name = name
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
+
+// compiledJSONPaths caches the compiled HiddenBodyPaths, which may be updated
+// at any time.
+var compiledJSONPaths struct {
+	mu       sync.Mutex
+	key      string
+	segments [][]jsonPathSegment
+}
+
+// hiddenBodyPathSegments returns the compiled HiddenBodyPaths. Invalid paths are
+// rejected by NewWithConfig, and skipped here.
+func hiddenBodyPathSegments() [][]jsonPathSegment {
+	key := strings.Join(HiddenBodyPaths, "\x00")
+
+	compiledJSONPaths.mu.Lock()
+	defer compiledJSONPaths.mu.Unlock()
+
+	if compiledJSONPaths.segments != nil && compiledJSONPaths.key == key {
+		return compiledJSONPaths.segments
+	}
+
+	paths := make([][]jsonPathSegment, 0, len(HiddenBodyPaths))
+	for _, path := range HiddenBodyPaths {
+		if segments, err := compileJSONPath(path); err == nil {
+			paths = append(paths, segments)
+		}
+	}
+
+	compiledJSONPaths.key = key
+	compiledJSONPaths.segments = paths
+	return paths
+}
+
+// validateHiddenBodyPaths returns the first invalid path of HiddenBodyPaths.
+func validateHiddenBodyPaths() error {
+	for _, path := range HiddenBodyPaths {
+		if _, err := compileJSONPath(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func matchJSONPath(segments []jsonPathSegment, path []jsonPathElement) bool {
+	if len(segments) == 0 {
+		return len(path) == 0
+	}
+
+	segment := segments[0]
+
+	if segment.recursive {
+		for i := range path {
+			if segment.match(path[i]) && matchJSONPath(segments[1:], path[i+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return len(path) > 0 && segment.match(path[0]) && matchJSONPath(segments[1:], path[1:])
+}
+
+// jsonRewriter re-encodes a JSON document token by token, replacing the values
+// of hidden fields and of containers nested deeper than maxDepth. It works on
+// truncated documents as well: the output then stops at the last complete token.
+type jsonRewriter struct {
+	fields   map[string]struct{}
+	paths    [][]jsonPathSegment
+	maxDepth int
+}
+
+func newJSONRewriter(maxDepth int) *jsonRewriter {
+	return &jsonRewriter{
+		fields:   HiddenBodyFields,
+		paths:    hiddenBodyPathSegments(),
+		maxDepth: maxDepth,
+	}
+}
+
+func (r *jsonRewriter) hidden(path []jsonPathElement) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	if last := path[len(path)-1]; !last.isIndex && len(r.fields) > 0 {
+		if _, found := r.fields[strings.ToLower(last.key)]; found {
+			return true
+		}
+	}
+
+	for _, segments := range r.paths {
+		if matchJSONPath(segments, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type jsonFrame struct {
+	object    bool
+	expectKey bool
+	count     int
+}
+
+type jsonRewriteResult struct {
+	out         []byte
+	changed     bool  // out differs from the input
+	valid       bool  // the input is a single complete document
+	cutInHidden bool  // the input is truncated inside a hidden value
+	err         error // the input is malformed
+}
+
+// rewrite returns the rewritten document. Bodies that don't look like JSON are
+// returned untouched. An error is returned for malformed documents, which may
+// hold hidden values the rewriter could not find: they must not be logged.
+// Truncated documents are not malformed.
+func (r *jsonRewriter) rewrite(body []byte) jsonRewriteResult {
+	if !looksLikeJSON(body) {
+		return jsonRewriteResult{out: body}
+	}
+	body = bytes.TrimPrefix(body, utf8BOM)
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	out := bytes.NewBuffer(make([]byte, 0, len(body)))
+	stack := []jsonFrame{}
+	path := []jsonPathElement{}
+	changed := false
+	valid := false
+	cutInHidden := false
+	documents := 0
+	var decodeErr error
+
+	// advance moves the parent container to its next element.
+	advance := func() {
+		if len(stack) == 0 {
+			documents++
+			return
+		}
+
+		top := &stack[len(stack)-1]
+		top.count++
+		if top.object {
+			top.expectKey = true
+		}
+		path = path[:len(path)-1]
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			decodeErr = err
+			valid = errors.Is(err, io.EOF) && len(stack) == 0 && documents == 1
+			// Truncated or invalid document: never return the original
+			// when it was cut inside a hidden value.
+			if len(stack) > 0 {
+				top := stack[len(stack)-1]
+				if !top.object && r.hidden(append(path, jsonPathElement{index: top.count, isIndex: true})) {
+					cutInHidden = true
+				} else if top.object && !top.expectKey && r.hidden(path) {
+					cutInHidden = true
+				}
+				changed = changed || (cutInHidden && !errors.Is(err, io.EOF))
+			}
+			break
+		}
+
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+
+			if top.object && top.expectKey {
+				if tok == json.Delim('}') {
+					out.WriteByte('}')
+					stack = stack[:len(stack)-1]
+					advance()
+					continue
+				}
+
+				key, _ := tok.(string)
+				if top.count > 0 {
+					out.WriteByte(',')
+				}
+				writeJSONString(out, key)
+				out.WriteByte(':')
+				top.expectKey = false
+				path = append(path, jsonPathElement{key: key})
+				continue
+			}
+
+			if !top.object {
+				if tok == json.Delim(']') {
+					out.WriteByte(']')
+					stack = stack[:len(stack)-1]
+					advance()
+					continue
+				}
+
+				if top.count > 0 {
+					out.WriteByte(',')
+				}
+				path = append(path, jsonPathElement{index: top.count, isIndex: true})
+			}
+		} else if documents > 0 {
+			out.WriteByte('\n')
+		}
+
+		if r.hidden(path) {
+			changed = true
+			writeJSONString(out, RedactedValue)
+
+			if delim, ok := tok.(json.Delim); ok && (delim == '{' || delim == '[') {
+				if err := skipJSONValue(dec); err != nil {
+					decodeErr = err
+					cutInHidden = true
+					break
+				}
+			}
+
+			advance()
+			continue
+		}
+
+		if _, ok := tok.(json.Delim); ok && r.maxDepth > 0 && len(stack) >= r.maxDepth {
+			changed = true
+			writeJSONString(out, "...")
+
+			if err := skipJSONValue(dec); err != nil {
+				decodeErr = err
+				break
+			}
+
+			advance()
+			continue
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteByte(byte(v))
+			stack = append(stack, jsonFrame{object: v == '{', expectKey: v == '{'})
+			continue
+		case string:
+			writeJSONString(out, v)
+		case json.Number:
+			out.WriteString(v.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(v))
+		case nil:
+			out.WriteString("null")
+		}
+
+		advance()
+	}
+
+	if decodeErr != nil && !isTruncatedJSON(decodeErr) {
+		return jsonRewriteResult{changed: true, err: decodeErr}
+	}
+
+	if !changed {
+		return jsonRewriteResult{out: body, valid: valid, cutInHidden: cutInHidden}
+	}
+
+	return jsonRewriteResult{out: out.Bytes(), changed: true, valid: valid, cutInHidden: cutInHidden}
+}
+
+// isTruncatedJSON returns true for decoding errors caused by the end of the input.
+func isTruncatedJSON(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// skipJSONValue consumes the remaining tokens of an object or array whose
+// opening delimiter has already been read.
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 1
+	for depth > 0 {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline
+}
+
+// hiddenBodyFieldNames returns HiddenBodyFields and the last names of HiddenBodyPaths.
+// The boolean is true when a path ends with a wildcard or an index, ie: any value may be hidden.
+func hiddenBodyFieldNames() ([]string, bool) {
+	names := make([]string, 0, len(HiddenBodyFields)+len(HiddenBodyPaths))
+	for name := range HiddenBodyFields {
+		names = append(names, name)
+	}
+
+	for _, segments := range hiddenBodyPathSegments() {
+		if len(segments) == 0 {
+			continue
+		}
+
+		last := segments[len(segments)-1]
+		if last.wildcard || last.isIndex {
+			return nil, true
+		}
+
+		names = append(names, last.name)
+	}
+
+	return names, false
+}
+
+// utf8BOM is the byte order mark some clients write before JSON documents.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+func looksLikeJSON(body []byte) bool {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(body, utf8BOM), " \t\r\n")
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// redactBody replaces the hidden fields of JSON bodies. Other bodies are returned untouched.
+// It returns false when the body looks like JSON but is malformed: it must not be logged.
+func redactBody(body []byte) ([]byte, bool) {
+	if len(HiddenBodyFields) == 0 && len(HiddenBodyPaths) == 0 {
+		return body, true
+	}
+
+	result := newJSONRewriter(0).rewrite(body)
+	return result.out, result.err == nil
+}
+
+// structuredJSONBody returns the body as a json.RawMessage, redacted and limited
+// to JSONBodyMaxDepth, so that it is logged as a structured value. It returns
+// false when the body is not a single complete JSON document.
+func structuredJSONBody(body []byte) (json.RawMessage, bool) {
+	if JSONBodyMaxSize > 0 && len(body) > JSONBodyMaxSize {
+		return nil, false
+	}
+
+	result := newJSONRewriter(JSONBodyMaxDepth).rewrite(body)
+	if result.err != nil || !result.valid {
+		return nil, false
+	}
+
+	// the body may belong to a pooled buffer
+	return json.RawMessage(bytes.Clone(result.out)), true
+}
+
+// isNDJSONContentType returns true for newline delimited JSON media types.
+func isNDJSONContentType(contentType string) bool {
+	switch parseMediaType(contentType) {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return true
+	}
+
+	return false
+}
+
+// isJSONContentType returns true for `application/json` and `+json` media types.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
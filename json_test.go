@@ -0,0 +1,246 @@
+package sloggin
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactBody(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body     string
+		expected string
+		ok       bool
+	}{
+		"hidden field":               {body: `{"a":1,"password":"hunter2"}`, expected: `{"a":1,"password":"[REDACTED]"}`, ok: true},
+		"case insensitive":           {body: `{"PassWord":"hunter2"}`, expected: `{"PassWord":"[REDACTED]"}`, ok: true},
+		"hidden container":           {body: `{"secret":{"pin":"1234"},"a":[1,2]}`, expected: `{"secret":"[REDACTED]","a":[1,2]}`, ok: true},
+		"nested field":               {body: `[{"user":{"token":"abc"}}]`, expected: `[{"user":{"token":"[REDACTED]"}}]`, ok: true},
+		"nothing hidden":             {body: `{"a": 1, "b": [true, null]}`, expected: `{"a": 1, "b": [true, null]}`, ok: true},
+		"not json":                   {body: `hello world`, expected: `hello world`, ok: true},
+		"truncated in hidden value":  {body: `{"a":1,"password":"hunt`, expected: `{"a":1,"password":`, ok: true},
+		"truncated in hidden object": {body: `{"secret":{"pin":"12`, expected: `{"secret":"[REDACTED]"`, ok: true},
+		"truncated after hidden":     {body: `{"password":"hunter2","b":`, expected: `{"password":"[REDACTED]","b":`, ok: true},
+		"truncated elsewhere":        {body: `{"a":1,"b":"some val`, expected: `{"a":1,"b":"some val`, ok: true},
+		"ndjson":                     {body: "{\"password\":\"x\"}\n{\"token\":\"y\",\"a\":1}\n", expected: "{\"password\":\"[REDACTED]\"}\n{\"token\":\"[REDACTED]\",\"a\":1}", ok: true},
+		"ndjson truncated":           {body: "{\"a\":1}\n{\"token\":\"y", expected: "{\"a\":1}\n{\"token\":", ok: true},
+		"byte order mark":            {body: "\ufeff{\"password\":\"hunter2\"}", expected: `{"password":"[REDACTED]"}`, ok: true},
+		"malformed literal":          {body: `{"a":NaN,"password":"hunter2"}`, ok: false},
+		"malformed quotes":           {body: `{'password':'hunter2'}`, ok: false},
+		"malformed separator":        {body: `{"a":1,,"password":"hunter2"}`, ok: false},
+		"trailing garbage":           {body: `{"a":1}x"password":"hunter2"`, ok: false},
+		"malformed in hidden value":  {body: `{"secret":{"pin":01234}}`, ok: false},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			out, ok := redactBody([]byte(tt.body))
+			if ok != tt.ok {
+				t.Fatalf("ok=%v, expected %v (output %q)", ok, tt.ok, out)
+			}
+
+			if ok && string(out) != tt.expected {
+				t.Fatalf("got %q, expected %q", out, tt.expected)
+			}
+
+			if strings.Contains(string(out), "hunter2") {
+				t.Fatalf("secret leaked: %q", out)
+			}
+		})
+	}
+}
+
+func TestStructuredJSONBody(t *testing.T) {
+	t.Parallel()
+
+	deep := strings.Repeat(`{"a":`, 12) + "1" + strings.Repeat("}", 12)
+
+	tests := map[string]struct {
+		body     string
+		expected string
+		ok       bool
+	}{
+		"document":  {body: `{"a":1,"password":"hunter2"}`, expected: `{"a":1,"password":"[REDACTED]"}`, ok: true},
+		"deep":      {body: deep, expected: strings.Repeat(`{"a":`, 10) + `"..."` + strings.Repeat("}", 10), ok: true},
+		"ndjson":    {body: "{\"a\":1}\n{\"b\":2}\n", ok: false},
+		"truncated": {body: `{"a":1,"b":`, ok: false},
+		"malformed": {body: `{"a":NaN}`, ok: false},
+		"not json":  {body: `hello`, ok: false},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			out, ok := structuredJSONBody([]byte(tt.body))
+			if ok != tt.ok || (ok && string(out) != tt.expected) {
+				t.Fatalf("got %q %v, expected %q %v", out, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCompileJSONPath(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"$":                 true,
+		"$.user.password":   true,
+		"$..token":          true,
+		"$.cards[*].number": true,
+		"$.items[0]":        true,
+		"$['user'].name":    true,
+		"$..[0]":            true,
+		"user.password":     false,
+		"$.":                false,
+		"$.items[0":         false,
+		"$.items[x]":        false,
+		"$user":             false,
+	}
+
+	for path, valid := range tests {
+		path, valid := path, valid
+		t.Run(path, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := compileJSONPath(path); (err == nil) != valid {
+				t.Fatalf("got error %v, expected valid=%v", err, valid)
+			}
+		})
+	}
+}
+
+// not parallel: updates global settings
+func TestHiddenBodyPaths(t *testing.T) {
+	paths := HiddenBodyPaths
+	defer func() {
+		HiddenBodyPaths = paths
+	}()
+
+	HiddenBodyPaths = []string{"$.user.email", "$.cards[*].number"}
+	body := `{"user":{"email":"a@b.c","name":"a"},"cards":[{"number":"4242"}],"email":"x"}`
+	expected := `{"user":{"email":"[REDACTED]","name":"a"},"cards":[{"number":"[REDACTED]"}],"email":"x"}`
+	if out, ok := redactBody([]byte(body)); !ok || string(out) != expected {
+		t.Fatalf("got %q %v, expected %q", out, ok, expected)
+	}
+
+	// the compiled paths follow updates
+	HiddenBodyPaths = []string{"$.email"}
+	expected = `{"user":{"email":"a@b.c","name":"a"},"cards":[{"number":"4242"}],"email":"[REDACTED]"}`
+	if out, ok := redactBody([]byte(body)); !ok || string(out) != expected {
+		t.Fatalf("got %q %v, expected %q", out, ok, expected)
+	}
+
+	HiddenBodyPaths = []string{"user.email"}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic on an invalid path")
+		}
+	}()
+	NewWithConfig(slog.New(slog.NewTextHandler(io.Discard, nil)), DefaultConfig())
+}
+
+func TestJSONBodyRecord(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	config := DefaultConfig()
+	config.WithRequestBody = true
+	config.WithResponseBody = true
+	config.WithJSONBody = true
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	router.POST("/login", func(c *gin.Context) {
+		_, _ = c.GetRawData()
+		c.Data(http.StatusOK, "text/plain", []byte(`{"a":1}`))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"user":"bob","password":"hunter2"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record struct {
+		Request struct {
+			Body any `json:"body"`
+		} `json:"request"`
+		Response struct {
+			Body any `json:"body"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(logs.String()), &record); err != nil {
+		t.Fatalf("invalid record: %v\n%s", err, logs.String())
+	}
+
+	// JSON content type: structured and redacted
+	body, ok := record.Request.Body.(map[string]any)
+	if !ok || body["user"] != "bob" || body["password"] != RedactedValue {
+		t.Errorf("unexpected request body: %#v", record.Request.Body)
+	}
+
+	// other content types: string
+	if record.Response.Body != `{"a":1}` {
+		t.Errorf("unexpected response body: %#v", record.Response.Body)
+	}
+}
+
+func TestBodyAttributesRedaction(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body        string
+		contentType string
+		sniffed     bool
+		expected    string
+	}{
+		"json":              {body: `{"password":"hunter2"}`, contentType: "application/json", expected: `{"password":"[REDACTED]"}`},
+		"json suffix":       {body: `{"password":"hunter2"}`, contentType: "application/vnd.api+json", expected: `{"password":"[REDACTED]"}`},
+		"byte order mark":   {body: "\ufeff{\"password\":\"hunter2\"}", contentType: "application/json; charset=utf-8", expected: `{"password":"[REDACTED]"}`},
+		"ndjson":            {body: "{\"token\":\"x\"}\n", contentType: "application/x-ndjson", expected: `{"token":"[REDACTED]"}`},
+		"sniffed":           {body: `{"password":"hunter2"}`, contentType: "text/plain; charset=utf-8", sniffed: true, expected: `{"password":"[REDACTED]"}`},
+		"text":              {body: "[WARN] disk full", contentType: "text/plain", expected: "[WARN] disk full"},
+		"csv":               {body: "[a],[b]", contentType: "text/csv", expected: "[a],[b]"},
+		"malformed json":    {body: `{'password':'hunter2'}`, contentType: "application/json", expected: ""},
+		"malformed sniffed": {body: `{'password':'hunter2'}`, contentType: "text/plain; charset=utf-8", sniffed: true, expected: ""},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			captured := capturedBody{
+				body:        []byte(tt.body),
+				length:      len(tt.body),
+				contentType: tt.contentType,
+				sniffed:     tt.sniffed,
+			}
+
+			got := map[string]string{}
+			for _, attr := range bodyAttributes(captured, Config{}) {
+				got[attr.Key] = attr.Value.String()
+			}
+
+			if got["body"] != tt.expected {
+				t.Fatalf("got %q, expected %q", got["body"], tt.expected)
+			}
+			if tt.expected == "" && got["body_redaction_failed"] != "true" {
+				t.Fatalf("expected body_redaction_failed: %v", got)
+			}
+		})
+	}
+}
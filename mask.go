@@ -0,0 +1,33 @@
+package sloggin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Masker replaces a sensitive value before it is logged.
+type Masker func(value string) string
+
+// MaskRedact replaces the whole value by RedactedValue.
+func MaskRedact(value string) string {
+	return RedactedValue
+}
+
+// MaskLast4 keeps only the last 4 characters of the value.
+// Values too short to be partially revealed are fully redacted.
+func MaskLast4(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 8 {
+		return RedactedValue
+	}
+
+	return strings.Repeat("*", 4) + string(runes[len(runes)-4:])
+}
+
+// MaskSHA256 replaces the value by a short SHA-256 fingerprint, so that
+// identical values can be correlated without being revealed.
+func MaskSHA256(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
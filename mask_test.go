@@ -0,0 +1,35 @@
+package sloggin
+
+import "testing"
+
+func TestMaskers(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		masker   Masker
+		value    string
+		expected string
+	}{
+		"redact":           {masker: MaskRedact, value: "secret", expected: RedactedValue},
+		"redact empty":     {masker: MaskRedact, value: "", expected: RedactedValue},
+		"last4":            {masker: MaskLast4, value: "0123456789f00d", expected: "****f00d"},
+		"last4 9 runes":    {masker: MaskLast4, value: "123456789", expected: "****6789"},
+		"last4 8 runes":    {masker: MaskLast4, value: "12345678", expected: RedactedValue},
+		"last4 short":      {masker: MaskLast4, value: "abc", expected: RedactedValue},
+		"last4 multibyte":  {masker: MaskLast4, value: "ééééééééé", expected: "****éééé"},
+		"last4 8 mb runes": {masker: MaskLast4, value: "éééééééé", expected: RedactedValue},
+		"sha256":           {masker: MaskSHA256, value: "test", expected: "sha256:9f86d081884c7d65"},
+		"sha256 empty":     {masker: MaskSHA256, value: "", expected: "sha256:e3b0c44298fc1c14"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.masker(tt.value); got != tt.expected {
+				t.Fatalf("got %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -2,10 +2,14 @@ package sloggin
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +19,9 @@ import (
 
 const (
 	customAttributesCtxKey = "slog-gin.custom-attributes"
+
+	// Non-standard status logged for requests aborted by the client (see Config.WithClientAbortStatus).
+	StatusClientClosedRequest = 499
 )
 
 var (
@@ -25,6 +32,60 @@ var (
 	RequestBodyMaxSize  = 64 * 1024 // 64KB
 	ResponseBodyMaxSize = 64 * 1024 // 64KB
 
+	// Limits of the request body read after the handler (see Config.WithUnreadRequestBody).
+	UnreadRequestBodyMaxSize = 64 * 1024 // 64KB
+	UnreadRequestBodyTimeout = 1 * time.Second
+
+	// Transfers of at least SlowTransferMinSize bytes, slower than SlowTransferMinThroughput
+	// bytes per second, are logged with Config.SlowTransferLevel (see Config.WithThroughput).
+	// Zero disables the rule.
+	SlowTransferMinSize             = 1024 * 1024 // 1MB
+	SlowTransferMinThroughput int64 = 0
+
+	// Server-Sent Events (see Config.WithSSE): number of events logged, maximum length of their data,
+	// and interval of progress records while the stream is open (zero disables them).
+	SSEFirstEvents        = 0
+	SSEEventDataMaxLength = 256
+	SSEProgressInterval   = 30 * time.Second
+
+	// Requests running longer than WatchdogThreshold are logged with Config.WatchdogLevel, then
+	// every WatchdogInterval until they end (see Config.WithWatchdog). Zero interval logs them once.
+	WatchdogThreshold = 30 * time.Second
+	WatchdogInterval  = 30 * time.Second
+
+	// When not zero, the last bytes of truncated bodies are logged too.
+	RequestBodyTailSize  = 0
+	ResponseBodyTailSize = 0
+
+	// Media types of bodies captured by WithRequestBody and WithResponseBody. Wildcards are supported.
+	// Other bodies are logged as a short preview. When empty, every body is captured.
+	CapturedBodyContentTypes = []string{
+		"application/graphql",
+		"application/javascript",
+		"application/json",
+		"application/*+json",
+		"application/protobuf",
+		"application/x-protobuf",
+		"application/*+protobuf",
+		"application/x-www-form-urlencoded",
+		"application/xml",
+		"application/*+xml",
+		"text/*",
+	}
+	// Per media type capture limits, overriding RequestBodyMaxSize and ResponseBodyMaxSize. Wildcards are supported.
+	BodyMaxSizeByContentType = map[string]int{}
+
+	// Maximum size of a compressed body once decoded, see BodyDecoders.
+	DecompressedBodyMaxSize = 1024 * 1024 // 1MB
+
+	// Fingerprint of full bodies (see Config.WithBodyHash).
+	BodyHashKey  = "body_sha256"
+	BodyHashFunc = sha256.New
+
+	// Preview of binary bodies.
+	BinaryBodyPreviewSize    = 32
+	BinaryBodyPreviewEncoder = hex.EncodeToString
+
 	HiddenRequestHeaders = map[string]struct{}{
 		"authorization": {},
 		"cookie":        {},
@@ -37,6 +98,60 @@ var (
 		"set-cookie": {},
 	}
 
+	// Lowercase. When not empty, only these headers are logged.
+	AllowedRequestHeaders  = map[string]struct{}{}
+	AllowedResponseHeaders = map[string]struct{}{}
+
+	// Lowercase. These headers are logged with a masked value. Takes precedence over hidden headers.
+	MaskedRequestHeaders  = map[string]Masker{}
+	MaskedResponseHeaders = map[string]Masker{}
+
+	// Case-insensitive. Values of these query parameters are replaced by RedactedValue.
+	HiddenQueryParams = map[string]struct{}{
+		"access_token":  {},
+		"api_key":       {},
+		"apikey":        {},
+		"client_secret": {},
+		"id_token":      {},
+		"password":      {},
+		"refresh_token": {},
+		"secret":        {},
+		"token":         {},
+	}
+	// Case-insensitive. When not empty, only these query parameters are logged.
+	AllowedQueryParams       = map[string]struct{}{}
+	QueryParamValueMaxLength = 256
+
+	// Case-insensitive. Values of cookies are masked with DefaultCookieMasker, unless listed here.
+	MaskedCookies              = map[string]Masker{}
+	DefaultCookieMasker Masker = MaskRedact
+
+	// Values of these fields are replaced by RedactedValue in JSON bodies.
+	// Field names are case-insensitive and matched at any depth.
+	HiddenBodyFields = map[string]struct{}{
+		"access_token":  {},
+		"api_key":       {},
+		"card_number":   {},
+		"client_secret": {},
+		"cvv":           {},
+		"password":      {},
+		"refresh_token": {},
+		"secret":        {},
+		"token":         {},
+	}
+	// JSON paths of values replaced by RedactedValue in JSON bodies, eg: "$.user.password", "$..token" or "$.cards[*].number".
+	// NewWithConfig panics on invalid paths.
+	HiddenBodyPaths = []string{}
+
+	// Limits of JSON bodies logged as structured values (see Config.WithJSONBody).
+	JSONBodyMaxDepth = 10
+	JSONBodyMaxSize  = 64 * 1024 // 64KB
+
+	// Maximum length of form field values (see Config.WithFormBody).
+	FormFieldValueMaxLength = 256
+
+	RedactedValue = "[REDACTED]"
+
 	// Formatted with http.CanonicalHeaderKey
 	RequestIDHeaderKey  = "X-Request-Id"
 	RequestIDContextKey = "slog-gin.request-id"
@@ -46,21 +161,60 @@ type Config struct {
 	DefaultLevel     slog.Level
 	ClientErrorLevel slog.Level
 	ServerErrorLevel slog.Level
-
-	WithUserAgent      bool
-	WithRequestID      bool
-	WithRequestBody    bool
-	WithRequestHeader  bool
-	WithResponseBody   bool
-	WithResponseHeader bool
-	WithSpanID         bool
-	WithTraceID        bool
-	WithClientIP       bool
-	WithCustomMessage  func(c *gin.Context) string
+	// Level of requests aborted by the client (see WithClientAbortStatus).
+	ClientAbortLevel slog.Level
+	// Minimum level of slow transfers (see WithThroughput).
+	SlowTransferLevel slog.Level
+	// Level of requests still running (see WithWatchdog).
+	WatchdogLevel slog.Level
+
+	WithUserAgent         bool
+	WithRequestID         bool
+	WithRawQuery          bool
+	WithQueryParams       bool
+	WithRequestBody       bool
+	WithRequestHeader     bool
+	WithRequestCookies    bool
+	WithResponseBody      bool
+	WithResponseHeader    bool
+	WithResponseCookies   bool
+	WithJSONBody          bool
+	WithFormBody          bool
+	WithBodyHash          bool
+	WithUnreadRequestBody bool
+	WithStreamingMetrics  bool
+	WithThroughput        bool
+	WithSSE               bool
+	WithHijackedConns     bool
+	WithRequestStart      bool
+	WithWatchdog          bool
+	WithSpanID            bool
+	WithTraceID           bool
+	WithClientIP          bool
+	WithClientAbortStatus bool
+	WithCustomMessage     func(c *gin.Context) string
+
+	// Decodes protobuf bodies into protojson.
+	ProtoRegistry *ProtoRegistry
+	// Keeps bodies larger than the inline limit, referenced by `body_ref`.
+	BodyStore BodyStore
+	// Keeps track of the requests being executed.
+	InflightRegistry *InflightRegistry
 
 	HandleGinDebug bool
 
 	Filters []Filter
+	// Filters evaluated before the handler, on the request only (method, path,
+	// host...). Ignored requests are not logged at all: neither the access
+	// record nor the start, watchdog, streaming and connection records, which
+	// Filters cannot suppress since they need the response.
+	RequestFilters []Filter
+
+	// When not empty, bodies are attached to the record only if one of these
+	// filters accepts the request, eg: sloggin.AcceptError(). Headers and
+	// cookies too, with RetentionIncludesHeaders.
+	RetentionFilters         []Filter
+	RetentionIncludesHeaders bool
 }
 
 // New returns a gin.HandlerFunc (middleware) that logs requests using slog.
@@ -84,29 +238,60 @@ func NewWithFilters(logger *slog.Logger, filters ...Filter) gin.HandlerFunc {
 // DefaultConfig returns the default configuration for the request logger.
 func DefaultConfig() Config {
 	return Config{
-		DefaultLevel:     slog.LevelInfo,
-		ClientErrorLevel: slog.LevelWarn,
-		ServerErrorLevel: slog.LevelError,
-
-		WithUserAgent:      false,
-		WithRequestID:      true,
-		WithRequestBody:    false,
-		WithRequestHeader:  false,
-		WithResponseBody:   false,
-		WithResponseHeader: false,
-		WithSpanID:         false,
-		WithTraceID:        false,
-		WithClientIP:       true,
-		WithCustomMessage:  nil,
+		DefaultLevel:      slog.LevelInfo,
+		ClientErrorLevel:  slog.LevelWarn,
+		ServerErrorLevel:  slog.LevelError,
+		ClientAbortLevel:  slog.LevelWarn,
+		SlowTransferLevel: slog.LevelWarn,
+		WatchdogLevel:     slog.LevelWarn,
+
+		WithUserAgent:         false,
+		WithRequestID:         true,
+		WithRawQuery:          true,
+		WithQueryParams:       false,
+		WithRequestBody:       false,
+		WithRequestHeader:     false,
+		WithRequestCookies:    false,
+		WithResponseBody:      false,
+		WithResponseHeader:    false,
+		WithResponseCookies:   false,
+		WithJSONBody:          false,
+		WithFormBody:          false,
+		WithBodyHash:          false,
+		WithUnreadRequestBody: false,
+		WithStreamingMetrics:  false,
+		WithThroughput:        false,
+		WithSSE:               false,
+		WithHijackedConns:     false,
+		WithRequestStart:      false,
+		WithWatchdog:          false,
+		WithSpanID:            false,
+		WithTraceID:           false,
+		WithClientIP:          true,
+		WithClientAbortStatus: false,
+		WithCustomMessage:     nil,
+
+		ProtoRegistry:    nil,
+		BodyStore:        nil,
+		InflightRegistry: nil,
 
 		HandleGinDebug: false,
 
-		Filters: []Filter{},
+		Filters:        []Filter{},
+		RequestFilters: []Filter{},
+
+		RetentionFilters:         []Filter{},
+		RetentionIncludesHeaders: false,
 	}
 }
 
 // NewWithConfig returns a gin.HandlerFunc (middleware) that logs requests using slog.
+// It panics when HiddenBodyPaths holds an invalid path.
 func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
+	if err := validateHiddenBodyPaths(); err != nil {
+		panic(err)
+	}
+
 	if config.HandleGinDebug {
 		SetDebugPrintRouteFunc(logger)
 		SetDebugPrintFunc(logger)
@@ -114,6 +299,7 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 
 	return func(c *gin.Context) {
 		start := time.Now()
+		ctx := c.Request.Context()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
 
@@ -131,15 +317,82 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 			c.Set(RequestIDContextKey, requestID)
 		}
 
+		for _, filter := range config.RequestFilters {
+			if !filter(c) {
+				c.Next()
+				return
+			}
+		}
+
 		// dump request body
-		br := newBodyReader(c.Request.Body, RequestBodyMaxSize, config.WithRequestBody)
+		br := newBodyReader(c.Request.Body, RequestBodyMaxSize, RequestBodyTailSize, config.WithRequestBody, c.Request.Header, config.WithFormBody, newBodySpill(c.Request.Context(), config.BodyStore), config.WithBodyHash)
 		c.Request.Body = br
+		defer br.release()
 
 		// dump response body
-		bw := newBodyWriter(c.Writer, ResponseBodyMaxSize, config.WithResponseBody)
+		writer := c.Writer
+		bw := newBodyWriter(c.Writer, ResponseBodyMaxSize, ResponseBodyTailSize, config.WithResponseBody, newBodySpill(c.Request.Context(), config.BodyStore), config.WithBodyHash)
 		c.Writer = bw
+		defer bw.release()
+
+		// in-flight requests
+		if config.InflightRegistry != nil {
+			defer config.InflightRegistry.add(&inflightRequest{
+				id:       requestID,
+				method:   c.Request.Method,
+				route:    c.FullPath(),
+				path:     path,
+				clientIP: c.ClientIP(),
+				start:    start,
+				reader:   br,
+				writer:   bw,
+			})()
+		}
+
+		// records linked to this request, emitted before it ends
+		var linkedRequest slog.Attr
+		var linkedBase []slog.Attr
+		if config.WithRequestStart || config.WithWatchdog || config.WithSSE || config.WithHijackedConns {
+			linkedRequest, linkedBase = linkedRecordAttributes(c, config, start, requestID)
+		}
+
+		if config.WithRequestStart {
+			logger.LogAttrs(ctx, config.DefaultLevel, "Request started", append([]slog.Attr{linkedRequest}, linkedBase...)...)
+		}
+
+		// long requests
+		var watchdog *repeater
+		if config.WithWatchdog && WatchdogThreshold > 0 {
+			watchdog = newRepeater(WatchdogThreshold, WatchdogInterval, func() {
+				logRequestRunning(ctx, logger, config.WatchdogLevel, start, linkedRequest, linkedBase)
+			})
+			defer watchdog.stop()
+		}
+
+		// event streams
+		if config.WithSSE {
+			bw.sse = newSSETracker()
+			bw.sse.every(SSEProgressInterval, func() {
+				logSSEProgress(ctx, logger, config.DefaultLevel, bw.sse, start, linkedRequest, linkedBase)
+			})
+			defer bw.sse.stop()
+		}
+
+		// hijacked connections (eg: WebSocket)
+		if config.WithHijackedConns {
+			bw.onHijackedConnClose = func(conn *hijackedConn) {
+				logHijackedConnClosed(context.WithoutCancel(ctx), logger, config.DefaultLevel, conn, linkedRequest, linkedBase)
+			}
+		}
 
 		c.Next()
+		end := time.Now()
+		latency := end.Sub(start)
+		watchdog.stop()
+		bw.sse.stop()
+
+		// the request context is canceled when the client goes away
+		canceled := errors.Is(ctx.Err(), context.Canceled)
 
 		// Pass thru filters and skip early the code below, to prevent unnecessary processing.
 		for _, filter := range config.Filters {
@@ -148,12 +401,21 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 			}
 		}
 
+		// capture the part of the request body the handler did not read
+		var readByHandler int
+		var sentByClient int64
+		if config.WithUnreadRequestBody {
+			readByHandler, sentByClient = br.drain(c.Request, writer, int64(UnreadRequestBodyMaxSize), UnreadRequestBodyTimeout)
+		}
+
 		status := c.Writer.Status()
+		aborted := config.WithClientAbortStatus && isClientAbort(canceled, bw.err)
+		if aborted {
+			status = StatusClientClosedRequest
+		}
 		method := c.Request.Method
 		host := c.Request.Host
 		route := c.FullPath()
-		end := time.Now()
-		latency := end.Sub(start)
 		userAgent := c.Request.UserAgent()
 		ip := c.ClientIP()
 		referer := c.Request.Referer()
@@ -167,7 +429,17 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 			slog.String("method", method),
 			slog.String("host", host),
 			slog.String("path", path),
-			slog.String("query", query),
+		)
+
+		if config.WithRawQuery {
+			requestAttributes = append(requestAttributes, slog.String("query", redactRawQuery(query)))
+		}
+
+		if config.WithQueryParams {
+			requestAttributes = append(requestAttributes, slog.Group("query_params", extractQueryParams(c.Request.URL.Query())...))
+		}
+
+		requestAttributes = append(requestAttributes,
 			slog.Any("params", params),
 			slog.String("route", route),
 			slog.String("referer", referer),
@@ -179,12 +451,28 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 			)
 		}
 
+		if canceled {
+			requestAttributes = append(requestAttributes, slog.Bool("canceled", true))
+		}
+
 		responseAttributes = append(responseAttributes,
 			slog.Time("time", end.UTC()),
 			slog.Duration("latency", latency),
 			slog.Int("status", status),
 		)
 
+		if bw.err != nil {
+			responseAttributes = append(responseAttributes, slog.String("write_error", bw.err.Error()))
+		}
+
+		if bw.hijacked {
+			responseAttributes = append(responseAttributes, slog.Bool("hijacked", true))
+		}
+
+		if config.WithStreamingMetrics {
+			responseAttributes = append(responseAttributes, streamingAttrs(bw, start)...)
+		}
+
 		if config.WithRequestID {
 			baseAttributes = append(baseAttributes, slog.String(RequestIDKey, requestID))
 		}
@@ -192,50 +480,82 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 		// otel
 		baseAttributes = append(baseAttributes, extractTraceSpanID(c.Request.Context(), config.WithTraceID, config.WithSpanID)...)
 
-		// request body
-		requestAttributes = append(requestAttributes, slog.Int("length", br.bytes))
-		if config.WithRequestBody {
-			requestAttributes = append(requestAttributes, slog.String("body", br.body.String()))
+		// bodies and headers retention
+		retained := len(config.RetentionFilters) == 0
+		for _, filter := range config.RetentionFilters {
+			if filter(c) {
+				retained = true
+				break
+			}
 		}
+		withHeaders := retained || !config.RetentionIncludesHeaders
 
-		// request headers
-		if config.WithRequestHeader {
-			kv := []any{}
-
-			for k, v := range c.Request.Header {
-				if _, found := HiddenRequestHeaders[strings.ToLower(k)]; found {
-					continue
-				}
-				kv = append(kv, slog.Any(k, v))
+		// request body
+		requestAttributes = append(requestAttributes, slog.Int("length", br.length()))
+		requestAttributes = append(requestAttributes, hashAttrs(br.hash, br.complete(c.Request.ContentLength))...)
+		requestAttributes = append(requestAttributes, bodyErrorAttrs(br.err)...)
+		requestThroughput, requestThroughputOK := br.throughput()
+		if config.WithThroughput && requestThroughputOK {
+			requestAttributes = append(requestAttributes, slog.Int64("throughput_bps", requestThroughput))
+		}
+		if config.WithUnreadRequestBody {
+			requestAttributes = append(requestAttributes,
+				slog.Int("bytes_read_by_handler", readByHandler),
+				slog.Int64("bytes_sent_by_client", sentByClient),
+			)
+			if br.unavailable {
+				requestAttributes = append(requestAttributes, slog.Bool("body_unavailable", true))
 			}
+		}
+		if config.WithRequestBody && retained {
+			body := br.captured()
+			body.proto = protoMessage(config.ProtoRegistry, c.Request, route, body.contentType, false)
+			requestAttributes = append(requestAttributes, bodyAttributes(body, config)...)
+			requestAttributes = append(requestAttributes, br.spill.attrs()...)
+		}
 
+		// request headers
+		if config.WithRequestHeader && withHeaders {
+			kv := extractHeaders(c.Request.Header, HiddenRequestHeaders, AllowedRequestHeaders, MaskedRequestHeaders)
 			requestAttributes = append(requestAttributes, slog.Group("header", kv...))
 		}
 
+		// request cookies
+		if config.WithRequestCookies && withHeaders {
+			requestAttributes = append(requestAttributes, slog.Group("cookies", extractRequestCookies(c.Request.Cookies())...))
+		}
+
 		if config.WithUserAgent {
 			requestAttributes = append(requestAttributes, slog.String("user-agent", userAgent))
 		}
 
 		// response body
-		responseAttributes = append(responseAttributes, slog.Int("length", bw.bytes))
-		if config.WithResponseBody {
-			responseAttributes = append(responseAttributes, slog.String("body", bw.body.String()))
+		responseAttributes = append(responseAttributes, slog.Int("length", bw.length()))
+		responseAttributes = append(responseAttributes, hashAttrs(bw.hash, true)...)
+		responseThroughput, responseThroughputOK := bw.throughput()
+		if config.WithThroughput && responseThroughputOK {
+			responseAttributes = append(responseAttributes, slog.Int64("throughput_bps", responseThroughput))
+		}
+		if bw.sse.isActive() {
+			responseAttributes = append(responseAttributes, bw.sse.attrs()...)
+		} else if config.WithResponseBody && retained {
+			body := bw.captured()
+			body.proto = protoMessage(config.ProtoRegistry, c.Request, route, body.contentType, true)
+			responseAttributes = append(responseAttributes, bodyAttributes(body, config)...)
+			responseAttributes = append(responseAttributes, bw.spill.attrs()...)
 		}
 
 		// response headers
-		if config.WithResponseHeader {
-			kv := []any{}
-
-			for k, v := range c.Writer.Header() {
-				if _, found := HiddenResponseHeaders[strings.ToLower(k)]; found {
-					continue
-				}
-				kv = append(kv, slog.Any(k, v))
-			}
-
+		if config.WithResponseHeader && withHeaders {
+			kv := extractHeaders(c.Writer.Header(), HiddenResponseHeaders, AllowedResponseHeaders, MaskedResponseHeaders)
 			responseAttributes = append(responseAttributes, slog.Group("header", kv...))
 		}
 
+		// response cookies
+		if config.WithResponseCookies && withHeaders {
+			responseAttributes = append(responseAttributes, slog.Group("cookies", extractResponseCookies(c.Writer.Header())...))
+		}
+
 		attributes := append(
 			[]slog.Attr{
 				{
@@ -259,12 +579,19 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 		}
 
 		level := config.DefaultLevel
-		if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		if aborted {
+			level = config.ClientAbortLevel
+		} else if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
 			level = config.ClientErrorLevel
 		} else if status >= http.StatusInternalServerError {
 			level = config.ServerErrorLevel
 		}
 
+		slow := isSlowTransfer(br.length(), requestThroughput, requestThroughputOK) || isSlowTransfer(bw.length(), responseThroughput, responseThroughputOK)
+		if config.WithThroughput && slow && level < config.SlowTransferLevel {
+			level = config.SlowTransferLevel
+		}
+
 		msg := "Incoming request"
 		if config.WithCustomMessage != nil {
 			msg = config.WithCustomMessage(c)
@@ -272,12 +599,12 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 			if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
 				msg = strings.TrimSuffix(c.Errors.String(), "\n")
 				if msg == "" {
-					msg = fmt.Sprintf("HTTP error: %d %s", status, strings.ToLower(http.StatusText(status)))
+					msg = fmt.Sprintf("HTTP error: %d %s", status, statusText(status))
 				}
 			} else if status >= http.StatusInternalServerError {
 				msg = strings.TrimSuffix(c.Errors.String(), "\n")
 				if msg == "" {
-					msg = fmt.Sprintf("HTTP error: %d %s", status, strings.ToLower(http.StatusText(status)))
+					msg = fmt.Sprintf("HTTP error: %d %s", status, statusText(status))
 				}
 			}
 		}
@@ -286,6 +613,46 @@ func NewWithConfig(logger *slog.Logger, config Config) gin.HandlerFunc {
 	}
 }
 
+// isClientAbort reports whether the client went away before the response was complete.
+func isClientAbort(canceled bool, writeErr error) bool {
+	return canceled || errors.Is(writeErr, syscall.EPIPE) || errors.Is(writeErr, syscall.ECONNRESET)
+}
+
+func statusText(status int) string {
+	if status == StatusClientClosedRequest {
+		return "client closed request"
+	}
+
+	return strings.ToLower(http.StatusText(status))
+}
+
+// linkedRecordAttributes returns the attributes of records linked to a request
+// (eg: progress of event streams, hijacked connections). They are computed
+// upfront: the request must not be accessed while the handler is running.
+func linkedRecordAttributes(c *gin.Context, config Config, start time.Time, requestID string) (slog.Attr, []slog.Attr) {
+	request := slog.Group("request",
+		slog.Time("time", start.UTC()),
+		slog.String("method", c.Request.Method),
+		slog.String("host", c.Request.Host),
+		slog.String("path", c.Request.URL.Path),
+		slog.String("route", c.FullPath()),
+	)
+
+	base := []slog.Attr{}
+	if config.WithRequestID {
+		base = append(base, slog.String(RequestIDKey, requestID))
+	}
+	base = append(base, extractTraceSpanID(c.Request.Context(), config.WithTraceID, config.WithSpanID)...)
+
+	return request, base
+}
+
+// logRequestRunning logs a request still running (see Config.WithWatchdog).
+func logRequestRunning(ctx context.Context, logger *slog.Logger, level slog.Level, start time.Time, request slog.Attr, base []slog.Attr) {
+	request.Value = slog.GroupValue(append(request.Value.Group(), slog.Duration("elapsed", time.Since(start)))...)
+	logger.LogAttrs(ctx, level, "Request still running", append([]slog.Attr{request}, base...)...)
+}
+
 // GetRequestID returns the request identifier.
 func GetRequestID(c *gin.Context) string {
 	requestID, ok := c.Get(RequestIDContextKey)
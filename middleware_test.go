@@ -0,0 +1,497 @@
+package sloggin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// not parallel: updates global settings
+func TestWatchdog(t *testing.T) {
+	threshold, interval := WatchdogThreshold, WatchdogInterval
+	WatchdogThreshold, WatchdogInterval = 10*time.Millisecond, 10*time.Millisecond
+	defer func() {
+		WatchdogThreshold, WatchdogInterval = threshold, interval
+	}()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	config := DefaultConfig()
+	config.WithRequestStart = true
+	config.WithWatchdog = true
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	router.GET("/slow", func(c *gin.Context) {
+		time.Sleep(50 * time.Millisecond)
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// no record after the request ended
+	output := logs.String()
+	time.Sleep(30 * time.Millisecond)
+	if logs.String() != output {
+		t.Fatalf("records after the end of the request:\n%s", strings.TrimPrefix(logs.String(), output))
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 3 {
+		t.Fatalf("expected start, watchdog and access records:\n%s", output)
+	}
+
+	if !strings.Contains(lines[0], `level=INFO msg="Request started" request.time=`) {
+		t.Errorf("unexpected start record: %s", lines[0])
+	}
+
+	running := strings.Count(output, `level=WARN msg="Request still running"`)
+	if running < 2 || running != len(lines)-2 {
+		t.Errorf("expected repeated watchdog records:\n%s", output)
+	}
+
+	if !strings.Contains(lines[len(lines)-1], `msg="Incoming request"`) || !strings.Contains(lines[len(lines)-1], "response.status=204") {
+		t.Errorf("unexpected access record: %s", lines[len(lines)-1])
+	}
+
+	// same request ID everywhere
+	id := lines[0][strings.LastIndex(lines[0], " id="):]
+	for _, line := range lines {
+		if !strings.HasSuffix(line, id) {
+			t.Errorf("request ID %q not found in: %s", id, line)
+		}
+	}
+}
+
+func TestBodyHash(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	config := DefaultConfig()
+	config.WithBodyHash = true
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	router.POST("/read", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.String(http.StatusOK, string(body))
+	})
+	router.POST("/unread", func(c *gin.Context) {
+		c.Status(http.StatusUnauthorized)
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	// sha256("hi") and sha256("")
+	const hi = "8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4"
+	const empty = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	tests := map[string]struct {
+		method   string
+		path     string
+		body     string
+		expected []string
+	}{
+		"read":   {method: http.MethodPost, path: "/read", body: "hi", expected: []string{"request.body_sha256=" + hi, "response.body_sha256=" + hi}},
+		"unread": {method: http.MethodPost, path: "/unread", body: "hi", expected: []string{"request.body_hash_partial=true", "response.body_sha256=" + empty}},
+		"empty":  {method: http.MethodGet, path: "/empty", expected: []string{"request.body_sha256=" + empty}},
+	}
+
+	for name, tt := range tests {
+		before := len(logs.String())
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+		output := logs.String()[before:]
+		for _, expected := range tt.expected {
+			if !strings.Contains(output, expected) {
+				t.Errorf("%s: %q not found in: %s", name, expected, output)
+			}
+		}
+		if name == "unread" && strings.Contains(output, "request.body_sha256") {
+			t.Errorf("%s: unexpected hash of a partly read body: %s", name, output)
+		}
+	}
+}
+
+// not parallel: updates global settings
+func TestUnreadRequestBody(t *testing.T) {
+	timeout := UnreadRequestBodyTimeout
+	UnreadRequestBodyTimeout = 100 * time.Millisecond
+	defer func() {
+		UnreadRequestBodyTimeout = timeout
+	}()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	config := DefaultConfig()
+	config.WithRequestBody = true
+	config.WithUnreadRequestBody = true
+	config.WithBodyHash = true
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	router.POST("/small", func(c *gin.Context) {
+		c.String(http.StatusUnauthorized, "denied")
+	})
+	router.POST("/large", func(c *gin.Context) {
+		c.String(http.StatusUnauthorized, strings.Repeat("x", 10*1024))
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	record := func(path string, send func()) string {
+		before := len(logs.String())
+		send()
+
+		// the record is written after the response
+		deadline := time.Now().Add(time.Second)
+		for !strings.Contains(logs.String()[before:], "msg=") && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+		return logs.String()[before:]
+	}
+
+	post := func(path string) func() {
+		return func() {
+			res, err := server.Client().Post(server.URL+path, "application/json", strings.NewReader(`{"a":1}`))
+			if err != nil {
+				t.Fatalf("POST %s: %v", path, err)
+			}
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+	}
+
+	// unread body, response still buffered
+	output := record("/small", post("/small"))
+	for _, expected := range []string{`request.body="{\"a\":1}"`, "request.bytes_read_by_handler=0", "request.bytes_sent_by_client=7", "request.body_sha256="} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("small: %q not found in: %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "body_unavailable") {
+		t.Errorf("small: unexpected body_unavailable: %s", output)
+	}
+
+	// headers sent before the end of the handler: net/http discards the body
+	output = record("/large", post("/large"))
+	for _, expected := range []string{`request.body=""`, "request.bytes_sent_by_client=7", "request.body_unavailable=true", "request.body_hash_partial=true"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("large: %q not found in: %s", expected, output)
+		}
+	}
+
+	// the client stops sending the body
+	output = record("/small", func() {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+
+		_, _ = conn.Write([]byte("POST /small HTTP/1.1\r\nHost: test\r\nContent-Length: 100\r\n\r\n{\"a\":"))
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _ = io.Copy(io.Discard, conn)
+	})
+	for _, expected := range []string{`request.body="{\"a\":"`, "request.bytes_sent_by_client=100", "request.body_hash_partial=true"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("timeout: %q not found in: %s", expected, output)
+		}
+	}
+
+	// the latency does not include the drain
+	start := strings.Index(output, "response.latency=") + len("response.latency=")
+	latency, err := time.ParseDuration(output[start : start+strings.IndexByte(output[start:], ' ')])
+	if err != nil || latency >= UnreadRequestBodyTimeout {
+		t.Errorf("timeout: unexpected latency %v (%v): %s", latency, err, output)
+	}
+}
+
+func TestRequestFilters(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	config := DefaultConfig()
+	config.WithRequestStart = true
+	config.RequestFilters = []Filter{IgnorePath("/healthz")}
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	router.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/users", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("handler not called: %d", recorder.Code)
+	}
+	if output := logs.String(); output != "" {
+		t.Fatalf("unexpected records for an ignored request:\n%s", output)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	output := logs.String()
+	if !strings.Contains(output, `msg="Request started"`) || !strings.Contains(output, `msg="Incoming request"`) {
+		t.Fatalf("expected start and access records:\n%s", output)
+	}
+}
+
+func TestRetentionFilters(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	config := DefaultConfig()
+	config.WithRequestBody = true
+	config.WithResponseBody = true
+	config.WithRequestHeader = true
+	config.RetentionFilters = []Filter{AcceptError()}
+	config.RetentionIncludesHeaders = true
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	router.POST("/items", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		if string(body) == "fail" {
+			c.String(http.StatusInternalServerError, "failed")
+			return
+		}
+		c.String(http.StatusOK, "created")
+	})
+
+	send := func(body string) string {
+		before := len(logs.String())
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		req.Header.Set("X-Custom", "value")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return logs.String()[before:]
+	}
+
+	// success: bodies and headers are dropped, lengths are kept
+	output := send("ok")
+	if strings.Contains(output, "request.body=") || strings.Contains(output, "response.body=") || strings.Contains(output, "request.header.") {
+		t.Errorf("unexpected bodies or headers on a 200:\n%s", output)
+	}
+	if !strings.Contains(output, "request.length=2") || !strings.Contains(output, "response.length=7") {
+		t.Errorf("expected lengths on a 200:\n%s", output)
+	}
+
+	// error: bodies and headers are kept
+	output = send("fail")
+	for _, expected := range []string{"request.body=fail", "response.body=failed", "request.header.X-Custom=[value]"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("%q not found on a 500:\n%s", expected, output)
+		}
+	}
+}
+
+func TestUnreadRequestBodyWithoutDeadline(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	config := DefaultConfig()
+	config.WithRequestBody = true
+	config.WithUnreadRequestBody = true
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	router.POST("/login", func(c *gin.Context) {
+		c.Status(http.StatusUnauthorized)
+	})
+
+	// a client that stalls, behind a recorder, which has no read deadlines
+	body, stall := io.Pipe()
+	defer stall.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/login", body)
+	req.ContentLength = 100
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		_ = stall.Close()
+		<-done
+		t.Fatal("the unread body was read without deadline")
+	}
+
+	output := logs.String()
+	if !strings.Contains(output, "request.bytes_read_by_handler=0") || !strings.Contains(output, "request.bytes_sent_by_client=100") {
+		t.Fatalf("unexpected record:\n%s", output)
+	}
+}
+
+type brokenPipeWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *brokenPipeWriter) Write(b []byte) (int, error) {
+	return 0, fmt.Errorf("write tcp 127.0.0.1:8080: %w", syscall.EPIPE)
+}
+
+func (w *brokenPipeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func TestClientAbort(t *testing.T) {
+	t.Parallel()
+
+	newRouter := func(logs *syncBuffer, withStatus bool) *gin.Engine {
+		config := DefaultConfig()
+		config.WithClientAbortStatus = withStatus
+		config.ClientAbortLevel = slog.LevelError
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+
+			c.Request = c.Request.WithContext(ctx)
+			c.Set("cancel", cancel)
+			if c.Request.URL.Path == "/broken" {
+				c.Writer = &brokenPipeWriter{ResponseWriter: c.Writer}
+			}
+			c.Next()
+		})
+		router.Use(NewWithConfig(slog.New(slog.NewTextHandler(logs, nil)), config))
+		router.GET("/canceled", func(c *gin.Context) {
+			c.MustGet("cancel").(context.CancelFunc)()
+			c.String(http.StatusOK, "too late")
+		})
+		router.GET("/broken", func(c *gin.Context) {
+			c.String(http.StatusOK, "hello")
+		})
+		return router
+	}
+
+	tests := map[string]struct {
+		path       string
+		withStatus bool
+		expected   []string
+		unexpected []string
+	}{
+		"canceled": {
+			path:       "/canceled",
+			withStatus: true,
+			expected:   []string{`level=ERROR msg="HTTP error: 499 client closed request"`, "request.canceled=true", "response.status=499"},
+		},
+		"canceled without status": {
+			path:       "/canceled",
+			withStatus: false,
+			expected:   []string{`level=INFO msg="Incoming request"`, "request.canceled=true", "response.status=200"},
+		},
+		"broken pipe": {
+			path:       "/broken",
+			withStatus: true,
+			expected:   []string{`level=ERROR msg="Error #01: write tcp 127.0.0.1:8080: broken pipe"`, `response.write_error="write tcp 127.0.0.1:8080: broken pipe"`, "response.status=499"},
+			unexpected: []string{"request.canceled"},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var logs syncBuffer
+			router := newRouter(&logs, tt.withStatus)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			output := logs.String()
+			for _, expected := range tt.expected {
+				if !strings.Contains(output, expected) {
+					t.Errorf("%q not found in: %s", expected, output)
+				}
+			}
+			for _, unexpected := range tt.unexpected {
+				if strings.Contains(output, unexpected) {
+					t.Errorf("unexpected %q in: %s", unexpected, output)
+				}
+			}
+		})
+	}
+}
+
+// not parallel: updates global settings
+func TestSlowTransferLevel(t *testing.T) {
+	minSize, minThroughput := SlowTransferMinSize, SlowTransferMinThroughput
+	defer func() {
+		SlowTransferMinSize, SlowTransferMinThroughput = minSize, minThroughput
+	}()
+	SlowTransferMinSize = 1024
+
+	var logs syncBuffer
+	config := DefaultConfig()
+	config.WithThroughput = true
+	config.SlowTransferLevel = slog.LevelWarn
+	config.ServerErrorLevel = slog.LevelError
+
+	router := gin.New()
+	router.Use(NewWithConfig(slog.New(slog.NewTextHandler(&logs, nil)), config))
+	slowly := func(status int, size int) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Status(status)
+			_, _ = c.Writer.WriteString(strings.Repeat("x", size/2))
+			time.Sleep(20 * time.Millisecond)
+			_, _ = c.Writer.WriteString(strings.Repeat("x", size/2))
+		}
+	}
+	router.GET("/large", slowly(http.StatusOK, 2048))
+	router.GET("/small", slowly(http.StatusOK, 512))
+	router.GET("/error", slowly(http.StatusInternalServerError, 2048))
+
+	tests := map[string]struct {
+		path          string
+		minThroughput int64
+		expected      string
+	}{
+		"large slow transfer":  {path: "/large", minThroughput: 1 << 30, expected: "level=WARN"},
+		"small slow transfer":  {path: "/small", minThroughput: 1 << 30, expected: "level=INFO"},
+		"server error":         {path: "/error", minThroughput: 1 << 30, expected: "level=ERROR"},
+		"throughput disabled":  {path: "/large", minThroughput: 0, expected: "level=INFO"},
+		"fast enough transfer": {path: "/large", minThroughput: 1, expected: "level=INFO"},
+	}
+
+	for name, tt := range tests {
+		SlowTransferMinThroughput = tt.minThroughput
+		before := len(logs.String())
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		output := logs.String()[before:]
+		if !strings.Contains(output, tt.expected) {
+			t.Errorf("%s: %q not found in: %s", name, tt.expected, output)
+		}
+	}
+}
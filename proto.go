@@ -0,0 +1,166 @@
+package sloggin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ProtoMessageFactory returns an empty message, used to decode protobuf bodies.
+type ProtoMessageFactory func() proto.Message
+
+type protoRoute struct {
+	request  ProtoMessageFactory
+	response ProtoMessageFactory
+}
+
+// ProtoRegistry maps routes and content types to protobuf messages, so that
+// protobuf bodies are logged as protojson. See Config.ProtoRegistry.
+type ProtoRegistry struct {
+	mu           sync.RWMutex
+	routes       map[string]protoRoute
+	contentTypes map[string]ProtoMessageFactory
+}
+
+// NewProtoRegistry returns an empty ProtoRegistry.
+func NewProtoRegistry() *ProtoRegistry {
+	return &ProtoRegistry{
+		routes:       map[string]protoRoute{},
+		contentTypes: map[string]ProtoMessageFactory{},
+	}
+}
+
+// RegisterRoute registers the request and response messages of a gin route,
+// eg: RegisterRoute(http.MethodPost, "/users/:id", ...). A nil factory is ignored.
+func (r *ProtoRegistry) RegisterRoute(method string, route string, request ProtoMessageFactory, response ProtoMessageFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes[strings.ToUpper(method)+" "+route] = protoRoute{
+		request:  request,
+		response: response,
+	}
+}
+
+// RegisterContentType registers the message of a media type,
+// eg: RegisterContentType("application/vnd.acme.user+protobuf", ...).
+func (r *ProtoRegistry) RegisterContentType(contentType string, factory ProtoMessageFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.contentTypes[strings.ToLower(contentType)] = factory
+}
+
+func (r *ProtoRegistry) lookup(method string, route string, contentType string, response bool) ProtoMessageFactory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if messages, found := r.routes[strings.ToUpper(method)+" "+route]; found {
+		if response && messages.response != nil {
+			return messages.response
+		} else if !response && messages.request != nil {
+			return messages.request
+		}
+	}
+
+	if factory, found := r.contentTypes[parseMediaType(contentType)]; found {
+		return factory
+	}
+
+	return nil
+}
+
+// isProtobufContentType returns true for protobuf media types, such as
+// `application/x-protobuf` or `application/vnd.acme.user+protobuf`.
+func isProtobufContentType(contentType string) bool {
+	mediaType := parseMediaType(contentType)
+	return mediaType == "application/x-protobuf" ||
+		mediaType == "application/protobuf" ||
+		mediaType == "application/x-google-protobuf" ||
+		mediaType == "application/vnd.google.protobuf" ||
+		strings.HasSuffix(mediaType, "+protobuf") ||
+		strings.HasSuffix(mediaType, "+proto")
+}
+
+// protoMessageFromContentType resolves messages announced in the content type,
+// eg: `application/x-protobuf; proto=acme.v1.User` or `messageType=acme.v1.User`,
+// from the global protobuf registry.
+func protoMessageFromContentType(contentType string) ProtoMessageFactory {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	name := params["proto"]
+	if name == "" {
+		name = params["messagetype"]
+	}
+	if name == "" {
+		return nil
+	}
+
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil
+	}
+
+	return func() proto.Message {
+		return messageType.New().Interface()
+	}
+}
+
+// protoBodyAttributes decodes a protobuf body into protojson. Unknown messages,
+// truncated or invalid bodies are logged as size plus hash. size is the full
+// length of the body, which exceeds len(body) when it is truncated.
+func protoBodyAttributes(body []byte, size int, truncated bool, factory ProtoMessageFactory, config Config) []slog.Attr {
+	if factory != nil && !truncated {
+		msg := factory()
+		if err := proto.Unmarshal(body, msg); err == nil {
+			out, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+			if err == nil {
+				attrs := []slog.Attr{slog.String("body_proto", string(msg.ProtoReflect().Descriptor().FullName()))}
+
+				if config.WithJSONBody {
+					if raw, ok := structuredJSONBody(out); ok {
+						return append(attrs, slog.Any("body", raw))
+					}
+				}
+
+				redacted, ok := redactBody(out)
+				if !ok {
+					return append(attrs, redactionFailedAttrs(out)...)
+				}
+
+				return append(attrs, slog.String("body", string(redacted)))
+			}
+		}
+	}
+
+	attrs := []slog.Attr{slog.Int("body_size", size)}
+	if !truncated {
+		sum := sha256.Sum256(body)
+		attrs = append(attrs, slog.String("body_hash", "sha256:"+hex.EncodeToString(sum[:])))
+	}
+
+	return attrs
+}
+
+// protoMessage returns the message of a request (or response) body.
+func protoMessage(registry *ProtoRegistry, req *http.Request, route string, contentType string, response bool) ProtoMessageFactory {
+	if registry != nil {
+		if factory := registry.lookup(req.Method, route, contentType, response); factory != nil {
+			return factory
+		}
+	}
+
+	return protoMessageFromContentType(contentType)
+}
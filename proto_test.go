@@ -0,0 +1,122 @@
+package sloggin
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtoBodyRecord(t *testing.T) {
+	t.Parallel()
+
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	registry := NewProtoRegistry()
+	registry.RegisterRoute(http.MethodPost, "/users/:id", func() proto.Message { return &structpb.Struct{} }, nil)
+	registry.RegisterContentType("application/vnd.acme.name+protobuf", func() proto.Message { return &wrapperspb.StringValue{} })
+
+	config := DefaultConfig()
+	config.WithRequestBody = true
+	config.WithResponseBody = true
+	config.ProtoRegistry = registry
+
+	router := gin.New()
+	router.Use(NewWithConfig(logger, config))
+	echo := func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.Data(http.StatusOK, c.GetHeader("Accept"), body)
+	}
+	router.POST("/users/:id", echo)
+	router.POST("/names", echo)
+
+	user, _ := structpb.NewStruct(map[string]any{"name": "bob", "password": "hunter2"})
+	userBody, _ := proto.Marshal(user)
+	nameBody, _ := proto.Marshal(wrapperspb.String("bob"))
+
+	tests := map[string]struct {
+		path        string
+		body        []byte
+		contentType string
+		expected    []string
+	}{
+		"route": {
+			path:        "/users/42",
+			body:        userBody,
+			contentType: "application/x-protobuf",
+			expected: []string{
+				"request.body_proto=google.protobuf.Struct",
+				`request.body="{\"name\":\"bob\",\"password\":\"[REDACTED]\"}"`,
+				// no response message registered for the route
+				"response.body_size=" + strconv.Itoa(len(userBody)) + " response.body_hash=sha256:",
+			},
+		},
+		"content type": {
+			path:        "/names",
+			body:        nameBody,
+			contentType: "application/vnd.acme.name+protobuf",
+			expected: []string{
+				"request.body_proto=google.protobuf.StringValue",
+				`request.body="\"bob\""`,
+				"response.body_proto=google.protobuf.StringValue",
+			},
+		},
+		"content type parameter": {
+			path:        "/names",
+			body:        nameBody,
+			contentType: "application/x-protobuf; proto=google.protobuf.StringValue",
+			expected: []string{
+				"request.body_proto=google.protobuf.StringValue",
+				`request.body="\"bob\""`,
+			},
+		},
+		"invalid": {
+			path:        "/names",
+			body:        []byte{0xff, 0xff, 0xff},
+			contentType: "application/vnd.acme.name+protobuf",
+			expected: []string{
+				"request.body_size=3 request.body_hash=sha256:",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		before := len(logs.String())
+
+		req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("Accept", tt.contentType)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		output := logs.String()[before:]
+		for _, expected := range tt.expected {
+			if !strings.Contains(output, expected) {
+				t.Errorf("%s: %q not found in: %s", name, expected, output)
+			}
+		}
+		if strings.Contains(output, "hunter2") {
+			t.Errorf("%s: secret leaked: %s", name, output)
+		}
+	}
+}
+
+func TestProtoBodyAttributesTruncated(t *testing.T) {
+	t.Parallel()
+
+	body, _ := proto.Marshal(wrapperspb.String("hello world"))
+	factory := func() proto.Message { return &wrapperspb.StringValue{} }
+
+	got := slog.GroupValue(protoBodyAttributes(body[:5], len(body), true, factory, Config{})...).String()
+	if expected := "[body_size=" + strconv.Itoa(len(body)) + "]"; got != expected {
+		t.Fatalf("unexpected attributes of a truncated body: %s", got)
+	}
+}
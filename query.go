@@ -0,0 +1,100 @@
+package sloggin
+
+import (
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// extractQueryParams turns decoded query parameters into slog attributes,
+// applying AllowedQueryParams, HiddenQueryParams and QueryParamValueMaxLength.
+func extractQueryParams(values url.Values) []any {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kv := make([]any, 0, len(keys))
+	for _, k := range keys {
+		lower := strings.ToLower(k)
+
+		if len(AllowedQueryParams) > 0 {
+			if _, found := AllowedQueryParams[lower]; !found {
+				continue
+			}
+		}
+
+		_, hidden := HiddenQueryParams[lower]
+
+		v := make([]string, 0, len(values[k]))
+		for _, value := range values[k] {
+			if hidden {
+				v = append(v, RedactedValue)
+			} else {
+				v = append(v, truncateString(value, QueryParamValueMaxLength))
+			}
+		}
+
+		kv = append(kv, slog.Any(k, v))
+	}
+
+	return kv
+}
+
+// redactRawQuery applies AllowedQueryParams, HiddenQueryParams and
+// QueryParamValueMaxLength to a raw query string, keeping the order and the
+// encoding of the parameters.
+func redactRawQuery(query string) string {
+	if query == "" || (len(HiddenQueryParams) == 0 && len(AllowedQueryParams) == 0 && QueryParamValueMaxLength <= 0) {
+		return query
+	}
+
+	pairs := strings.Split(query, "&")
+	kept := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+
+		rawKey, rawValue, hasValue := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		lower := strings.ToLower(key)
+
+		if len(AllowedQueryParams) > 0 {
+			if _, found := AllowedQueryParams[lower]; !found {
+				continue
+			}
+		}
+
+		if _, hidden := HiddenQueryParams[lower]; hidden && hasValue {
+			kept = append(kept, rawKey+"="+RedactedValue)
+		} else if hasValue {
+			kept = append(kept, rawKey+"="+truncateString(rawValue, QueryParamValueMaxLength))
+		} else {
+			kept = append(kept, rawKey)
+		}
+	}
+
+	return strings.Join(kept, "&")
+}
+
+// truncateString cuts s to at most maxLength bytes, without splitting a rune.
+// A negative or zero maxLength disables truncation.
+func truncateString(s string, maxLength int) string {
+	if maxLength <= 0 || len(s) <= maxLength {
+		return s
+	}
+
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return s[:cut] + "..."
+}
@@ -0,0 +1,109 @@
+package sloggin
+
+import (
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactRawQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		query    string
+		expected string
+	}{
+		"empty":            {query: "", expected: ""},
+		"nothing hidden":   {query: "page=2&q=a%20b", expected: "page=2&q=a%20b"},
+		"hidden":           {query: "page=2&access_token=abc", expected: "page=2&access_token=[REDACTED]"},
+		"case insensitive": {query: "Access_Token=abc&page=2", expected: "Access_Token=[REDACTED]&page=2"},
+		"escaped key":      {query: "access%5Ftoken=abc", expected: "access%5Ftoken=[REDACTED]"},
+		"repeated":         {query: "token=a&token=b", expected: "token=[REDACTED]&token=[REDACTED]"},
+		"no value":         {query: "debug&token", expected: "debug&token"},
+		"long value":       {query: "q=" + strings.Repeat("a", 300), expected: "q=" + strings.Repeat("a", 256) + "..."},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := redactRawQuery(tt.query); got != tt.expected {
+				t.Fatalf("got %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractQueryParams(t *testing.T) {
+	t.Parallel()
+
+	values, _ := url.ParseQuery("page=2&access_token=abc&q=" + strings.Repeat("é", 200))
+
+	got := map[string]string{}
+	for _, attr := range extractQueryParams(values) {
+		attr := attr.(slog.Attr)
+		got[attr.Key] = attr.Value.String()
+	}
+
+	expected := map[string]string{
+		"page":         "[2]",
+		"access_token": "[" + RedactedValue + "]",
+		"q":            "[" + strings.Repeat("é", 128) + "...]",
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("%s: got %q, expected %q", k, got[k], v)
+		}
+	}
+}
+
+// not parallel: updates global settings
+func TestQueryAllowedParams(t *testing.T) {
+	allowed := AllowedQueryParams
+	AllowedQueryParams = map[string]struct{}{"page": {}, "token": {}}
+	defer func() {
+		AllowedQueryParams = allowed
+	}()
+
+	if got := redactRawQuery("page=2&q=secret&Token=abc"); got != "page=2&Token=[REDACTED]" {
+		t.Fatalf("unexpected raw query: %q", got)
+	}
+
+	values, _ := url.ParseQuery("page=2&q=secret&Token=abc")
+	attrs := extractQueryParams(values)
+	if len(attrs) != 2 || attrs[0].(slog.Attr).Key != "Token" || attrs[1].(slog.Attr).Key != "page" {
+		t.Fatalf("unexpected query params: %v", attrs)
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		s         string
+		maxLength int
+		expected  string
+	}{
+		"short":      {s: "hello", maxLength: 10, expected: "hello"},
+		"ascii":      {s: "hello world", maxLength: 5, expected: "hello..."},
+		"mid rune":   {s: "héllo", maxLength: 2, expected: "h..."},
+		"rune end":   {s: "héllo", maxLength: 3, expected: "hé..."},
+		"emoji":      {s: "a😀b", maxLength: 4, expected: "a..."},
+		"disabled":   {s: "hello", maxLength: 0, expected: "hello"},
+		"negative":   {s: "hello", maxLength: -1, expected: "hello"},
+		"first rune": {s: "😀", maxLength: 2, expected: "..."},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := truncateString(tt.s, tt.maxLength); got != tt.expected {
+				t.Fatalf("got %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package sloggin
+
+import (
+	"sync"
+	"time"
+)
+
+// repeater calls a function after a delay, then at every interval, until it
+// is stopped.
+type repeater struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+	running sync.WaitGroup
+}
+
+func newRepeater(delay time.Duration, interval time.Duration, fn func()) *repeater {
+	r := &repeater{}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timer = time.AfterFunc(delay, func() {
+		r.mu.Lock()
+		if r.stopped {
+			r.mu.Unlock()
+			return
+		}
+		r.running.Add(1)
+		r.mu.Unlock()
+
+		defer r.running.Done()
+
+		fn()
+
+		r.mu.Lock()
+		if !r.stopped && interval > 0 {
+			r.timer.Reset(interval)
+		}
+		r.mu.Unlock()
+	})
+
+	return r
+}
+
+// stop cancels the next calls, and waits for the current one to return, so
+// that no goroutine outlives the request. It must not be called from fn.
+func (r *repeater) stop() {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	r.stopped = true
+	r.timer.Stop()
+	r.mu.Unlock()
+
+	r.running.Wait()
+}
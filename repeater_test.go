@@ -0,0 +1,57 @@
+package sloggin
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRepeater(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	r := newRepeater(time.Millisecond, time.Millisecond, func() {
+		calls.Add(1)
+		time.Sleep(time.Millisecond)
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	r.stop()
+
+	stopped := calls.Load()
+	if stopped < 3 {
+		t.Fatalf("called %d times", stopped)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if calls.Load() != stopped {
+		t.Fatalf("called %d times after stop", calls.Load()-stopped)
+	}
+
+	// stopping twice, or a nil repeater, is a no-op
+	r.stop()
+	var nilRepeater *repeater
+	nilRepeater.stop()
+}
+
+func TestRepeaterOnce(t *testing.T) {
+	t.Parallel()
+
+	done := make(chan struct{})
+	var calls atomic.Int32
+	r := newRepeater(time.Millisecond, 0, func() {
+		if calls.Add(1) == 1 {
+			close(done)
+		}
+	})
+	defer r.stop()
+
+	<-done
+	time.Sleep(10 * time.Millisecond)
+	if calls.Load() != 1 {
+		t.Fatalf("called %d times, expected once", calls.Load())
+	}
+}
@@ -0,0 +1,60 @@
+package sloggin
+
+// ringBuffer keeps the last bytes written to it.
+type ringBuffer struct {
+	buf  []byte
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{
+		buf: make([]byte, size),
+	}
+}
+
+// implements io.Writer
+func (r *ringBuffer) Write(b []byte) (int, error) {
+	n := len(b)
+	size := len(r.buf)
+	if size == 0 || n == 0 {
+		return n, nil
+	}
+
+	if n >= size {
+		copy(r.buf, b[n-size:])
+		r.pos = 0
+		r.full = true
+		return n, nil
+	}
+
+	copied := copy(r.buf[r.pos:], b)
+	if copied < n {
+		copy(r.buf, b[copied:])
+		r.full = true
+	}
+
+	r.pos = (r.pos + n) % size
+	if r.pos == 0 {
+		r.full = true
+	}
+
+	return n, nil
+}
+
+// Bytes returns the last bytes written, in order.
+func (r *ringBuffer) Bytes() []byte {
+	if !r.full {
+		return append([]byte{}, r.buf[:r.pos]...)
+	}
+
+	return append(append([]byte{}, r.buf[r.pos:]...), r.buf[:r.pos]...)
+}
+
+func (r *ringBuffer) Len() int {
+	if r.full {
+		return len(r.buf)
+	}
+
+	return r.pos
+}
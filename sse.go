@@ -0,0 +1,285 @@
+package sloggin
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// field lines longer than this are truncated
+const sseLineMaxSize = 1024
+
+type sseTypeStats struct {
+	events int
+	bytes  int
+}
+
+type sseEvent struct {
+	event string
+	id    string
+	data  []byte
+}
+
+// sseTracker parses a Server-Sent Events stream, as it is written to the
+// client. It is safe for concurrent use, so that progress records can be
+// emitted while the stream is open.
+type sseTracker struct {
+	mu     sync.Mutex
+	active bool
+	status int
+	events int
+	bytes  int
+	types  map[string]*sseTypeStats
+	first  []sseEvent
+
+	progressRepeater *repeater
+
+	// parser state
+	line       []byte
+	skipLF     bool
+	inEvent    bool
+	eventBytes int
+	event      sseEvent
+	hasData    bool
+}
+
+func newSSETracker() *sseTracker {
+	return &sseTracker{
+		types: map[string]*sseTypeStats{},
+	}
+}
+
+// activate starts tracking, once the response is known to be an event stream.
+func (t *sseTracker) activate(status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active = true
+	t.status = status
+}
+
+// implements io.Writer
+func (t *sseTracker) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.active {
+		return len(b), nil
+	}
+
+	t.bytes += len(b)
+	for _, c := range b {
+		t.eventBytes++
+
+		if t.skipLF {
+			t.skipLF = false
+			if c == '\n' {
+				continue
+			}
+		}
+
+		switch c {
+		case '\r':
+			t.skipLF = true
+			t.endLine()
+		case '\n':
+			t.endLine()
+		default:
+			if len(t.line) < sseLineMaxSize {
+				t.line = append(t.line, c)
+			}
+		}
+	}
+
+	return len(b), nil
+}
+
+func (t *sseTracker) endLine() {
+	line := t.line
+	t.line = t.line[:0]
+
+	// a blank line dispatches the event
+	if len(line) == 0 {
+		if t.inEvent {
+			t.dispatch()
+		}
+		t.eventBytes = 0
+		return
+	}
+
+	// comment (eg: keep-alive)
+	if line[0] == ':' {
+		return
+	}
+
+	name, value := line, []byte{}
+	for i, c := range line {
+		if c == ':' {
+			name, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+			break
+		}
+	}
+
+	switch string(name) {
+	case "event":
+		t.event.event = string(value)
+	case "id":
+		t.event.id = string(value)
+	case "data":
+		if len(t.first) < SSEFirstEvents && len(t.event.data) <= SSEEventDataMaxLength {
+			if t.hasData {
+				t.event.data = append(t.event.data, '\n')
+			}
+			t.event.data = append(t.event.data, value...)
+		}
+		t.hasData = true
+	case "retry":
+	default:
+		return
+	}
+
+	t.inEvent = true
+}
+
+func (t *sseTracker) dispatch() {
+	event := t.event.event
+	if event == "" {
+		event = "message"
+	}
+
+	stats, ok := t.types[event]
+	if !ok {
+		stats = &sseTypeStats{}
+		t.types[event] = stats
+	}
+	stats.events++
+	stats.bytes += t.eventBytes
+	t.events++
+
+	if len(t.first) < SSEFirstEvents {
+		t.first = append(t.first, sseEvent{
+			event: event,
+			id:    t.event.id,
+			data:  t.event.data,
+		})
+	}
+
+	t.event = sseEvent{}
+	t.inEvent = false
+	t.hasData = false
+}
+
+// every calls fn periodically while the stream is open, until stop is called.
+func (t *sseTracker) every(interval time.Duration, fn func()) {
+	if t == nil || interval <= 0 {
+		return
+	}
+
+	t.progressRepeater = newRepeater(interval, interval, func() {
+		if t.isActive() {
+			fn()
+		}
+	})
+}
+
+func (t *sseTracker) stop() {
+	if t == nil {
+		return
+	}
+
+	t.progressRepeater.stop()
+}
+
+func (t *sseTracker) isActive() bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.active
+}
+
+// progress returns the status and the number of bytes written so far.
+func (t *sseTracker) progress() (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.status, t.bytes
+}
+
+func (t *sseTracker) attrs() []slog.Attr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make([]string, 0, len(t.types))
+	for name := range t.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	types := make([]any, 0, len(names))
+	for _, name := range names {
+		types = append(types, slog.Group(name,
+			slog.Int("events", t.types[name].events),
+			slog.Int("bytes", t.types[name].bytes),
+		))
+	}
+
+	attrs := []any{
+		slog.Int("events", t.events),
+		slog.Group("types", types...),
+	}
+
+	if len(t.first) > 0 {
+		events := make([]map[string]any, 0, len(t.first))
+		for _, event := range t.first {
+			e := map[string]any{
+				"event": event.event,
+				"data":  truncateString(string(event.data), SSEEventDataMaxLength),
+			}
+			if event.id != "" {
+				e["id"] = event.id
+			}
+			events = append(events, e)
+		}
+		attrs = append(attrs, slog.Any("first_events", events))
+	}
+
+	return []slog.Attr{slog.Group("sse", attrs...)}
+}
+
+func isEventStream(contentType string) bool {
+	return parseMediaType(contentType) == "text/event-stream"
+}
+
+// logSSEProgress logs an open event stream.
+func logSSEProgress(ctx context.Context, logger *slog.Logger, level slog.Level, tracker *sseTracker, start time.Time, request slog.Attr, base []slog.Attr) {
+	status, length := tracker.progress()
+
+	response := []slog.Attr{
+		slog.Duration("latency", time.Since(start)),
+		slog.Int("status", status),
+		slog.Int("length", length),
+	}
+	response = append(response, tracker.attrs()...)
+
+	attributes := append(
+		[]slog.Attr{
+			request,
+			{
+				Key:   "response",
+				Value: slog.GroupValue(response...),
+			},
+		},
+		base...,
+	)
+
+	logger.LogAttrs(ctx, level, "Streaming request", attributes...)
+}
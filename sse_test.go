@@ -0,0 +1,95 @@
+package sloggin
+
+import (
+	"bytes"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sse"
+)
+
+func TestSSETracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := newSSETracker()
+	tracker.activate(200)
+
+	var stream bytes.Buffer
+	_ = sse.Encode(&stream, sse.Event{Event: "tick", Data: "1"})
+	_ = sse.Encode(&stream, sse.Event{Event: "tick", Id: "2", Data: "2"})
+	_ = sse.Encode(&stream, sse.Event{Data: "multi\nline"})
+	stream.WriteString(": keep-alive\r\n\r\n")
+	stream.WriteString("event: done\r\ndata: bye\r\n\r\n")
+
+	// split writes, as the stream is written to the client
+	for _, b := range stream.Bytes() {
+		_, _ = tracker.Write([]byte{b})
+	}
+
+	if tracker.events != 4 || tracker.bytes != stream.Len() {
+		t.Fatalf("events=%d bytes=%d, expected 4 events and %d bytes", tracker.events, tracker.bytes, stream.Len())
+	}
+
+	for name, expected := range map[string]int{"tick": 2, "message": 1, "done": 1} {
+		if stats := tracker.types[name]; stats == nil || stats.events != expected {
+			t.Fatalf("%s: %+v, expected %d events", name, stats, expected)
+		}
+	}
+
+	if bytes := tracker.types["done"].bytes; bytes != len("event: done\r\ndata: bye\r\n\r\n") {
+		t.Fatalf("done: %d bytes", bytes)
+	}
+}
+
+func TestSSETrackerInactive(t *testing.T) {
+	t.Parallel()
+
+	tracker := newSSETracker()
+	_, _ = tracker.Write([]byte("data: ignored\n\n"))
+
+	if tracker.events != 0 || tracker.bytes != 0 || tracker.isActive() {
+		t.Fatalf("inactive tracker recorded %d events", tracker.events)
+	}
+
+	var nilTracker *sseTracker
+	nilTracker.every(time.Millisecond, func() {})
+	nilTracker.stop()
+}
+
+func TestSSETrackerProgress(t *testing.T) {
+	t.Parallel()
+
+	tracker := newSSETracker()
+	tracker.activate(200)
+
+	var calls atomic.Int32
+	tracker.every(5*time.Millisecond, func() {
+		_ = tracker.attrs()
+		calls.Add(1)
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for calls.Load() < 3 && time.Now().Before(deadline) {
+		_, _ = tracker.Write([]byte("data: x\n\n"))
+		time.Sleep(time.Millisecond)
+	}
+	tracker.stop()
+
+	if calls.Load() < 3 {
+		t.Fatalf("progress called %d times", calls.Load())
+	}
+
+	// no call after stop, besides one in flight
+	stopped := calls.Load()
+	time.Sleep(30 * time.Millisecond)
+	if calls.Load() > stopped+1 {
+		t.Fatalf("progress called %d times after stop", calls.Load()-stopped)
+	}
+
+	group := tracker.attrs()[0]
+	if group.Key != "sse" || group.Value.Kind() != slog.KindGroup {
+		t.Fatalf("unexpected attributes: %v", group)
+	}
+}
@@ -0,0 +1,259 @@
+package sloggin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BodyStore keeps full bodies that are too large to be logged inline.
+// See Config.BodyStore.
+type BodyStore interface {
+	// Create returns a sink receiving a body, chunk by chunk.
+	Create(ctx context.Context) (BodySink, error)
+}
+
+// BodySink receives a body. The reference returned by Commit is logged as `body_ref`.
+type BodySink interface {
+	Write(b []byte) (int, error)
+	Commit() (ref string, err error)
+	Abort() error
+}
+
+var _ BodyStore = (*FileBodyStore)(nil)
+
+// FileBodyStore writes bodies to a local directory, under their SHA-256 hash.
+// Identical bodies are stored once.
+type FileBodyStore struct {
+	Dir string
+
+	// Files older than MaxAge are removed, at most once per CleanupInterval.
+	// Zero MaxAge keeps files forever.
+	MaxAge          time.Duration
+	CleanupInterval time.Duration
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+	cleaning    sync.WaitGroup // background cleanup
+	running     bool
+}
+
+// NewFileBodyStore returns a FileBodyStore writing to dir.
+func NewFileBodyStore(dir string, maxAge time.Duration) *FileBodyStore {
+	return &FileBodyStore{
+		Dir:             dir,
+		MaxAge:          maxAge,
+		CleanupInterval: time.Minute,
+	}
+}
+
+// implements BodyStore
+func (s *FileBodyStore) Create(ctx context.Context) (BodySink, error) {
+	if err := os.MkdirAll(s.Dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileBodySink{
+		store: s,
+		file:  file,
+		hash:  sha256.New(),
+	}, nil
+}
+
+// Cleanup removes the bodies older than MaxAge. Temporary files of bodies
+// being written and files not created by the store are kept.
+func (s *FileBodyStore) Cleanup() error {
+	if s.MaxAge <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.lastCleanup = time.Now()
+	s.mu.Unlock()
+
+	deadline := time.Now().Add(-s.MaxAge)
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isBodyStoreName(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(deadline) {
+			continue
+		}
+
+		_ = os.Remove(filepath.Join(s.Dir, entry.Name()))
+	}
+
+	return nil
+}
+
+// maybeCleanup starts a cleanup in the background, when due, so that requests
+// do not wait for the directory to be scanned.
+func (s *FileBodyStore) maybeCleanup() {
+	if s.MaxAge <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running || time.Since(s.lastCleanup) < s.CleanupInterval {
+		return
+	}
+
+	s.running = true
+	s.cleaning.Add(1)
+	go func() {
+		defer s.cleaning.Done()
+
+		_ = s.Cleanup()
+
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+}
+
+// isBodyStoreName returns true for the names of stored bodies: hex-encoded SHA-256 hashes.
+func isBodyStoreName(name string) bool {
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+type fileBodySink struct {
+	store *FileBodyStore
+	file  *os.File
+	hash  hash.Hash
+}
+
+func (s *fileBodySink) Write(b []byte) (int, error) {
+	s.hash.Write(b)
+	return s.file.Write(b)
+}
+
+func (s *fileBodySink) Commit() (string, error) {
+	if err := s.file.Close(); err != nil {
+		_ = os.Remove(s.file.Name())
+		return "", err
+	}
+
+	name := filepath.Join(s.store.Dir, hex.EncodeToString(s.hash.Sum(nil)))
+
+	if _, err := os.Stat(name); err == nil {
+		// already stored: refresh its age
+		_ = os.Remove(s.file.Name())
+		now := time.Now()
+		_ = os.Chtimes(name, now, now)
+	} else if err := os.Rename(s.file.Name(), name); err != nil {
+		_ = os.Remove(s.file.Name())
+		return "", err
+	}
+
+	s.store.maybeCleanup()
+
+	return name, nil
+}
+
+func (s *fileBodySink) Abort() error {
+	_ = s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// bodySpill sends a body to a BodyStore, once it outgrows the inline capture.
+type bodySpill struct {
+	ctx       context.Context
+	store     BodyStore
+	sink      BodySink
+	err       error
+	ref       string
+	committed bool
+}
+
+func newBodySpill(ctx context.Context, store BodyStore) *bodySpill {
+	if store == nil {
+		return nil
+	}
+
+	return &bodySpill{
+		ctx:   ctx,
+		store: store,
+	}
+}
+
+// write receives a chunk. captured is the body received so far, before this
+// chunk, and limit is the inline capture limit.
+func (s *bodySpill) write(captured []byte, b []byte, limit int) {
+	if s == nil || s.err != nil {
+		return
+	}
+
+	if s.sink == nil {
+		if len(captured)+len(b) <= limit {
+			return
+		}
+
+		s.sink, s.err = s.store.Create(s.ctx)
+		if s.err != nil {
+			return
+		}
+
+		_, s.err = s.sink.Write(captured)
+		if s.err != nil {
+			return
+		}
+	}
+
+	_, s.err = s.sink.Write(b)
+}
+
+// attrs commits the body and returns its reference.
+func (s *bodySpill) attrs() []slog.Attr {
+	if s == nil || (s.sink == nil && s.err == nil) {
+		return []slog.Attr{}
+	}
+
+	if s.err == nil && !s.committed {
+		s.ref, s.err = s.sink.Commit()
+		s.committed = s.err == nil
+	}
+
+	if s.err != nil {
+		return []slog.Attr{slog.String("body_ref_error", s.err.Error())}
+	}
+
+	return []slog.Attr{slog.String("body_ref", s.ref)}
+}
+
+// release drops a body that was not committed.
+func (s *bodySpill) release() {
+	if s != nil && s.sink != nil && !s.committed {
+		_ = s.sink.Abort()
+		s.sink = nil
+	}
+}
@@ -0,0 +1,98 @@
+package sloggin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileBodyStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := NewFileBodyStore(dir, time.Hour)
+
+	write := func(body string) string {
+		sink, err := store.Create(context.Background())
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		_, _ = sink.Write([]byte(body))
+
+		ref, err := sink.Commit()
+		if err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+		return ref
+	}
+
+	ref := write("hello world")
+	store.cleaning.Wait()
+
+	// sha256("hello world")
+	if filepath.Base(ref) != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" {
+		t.Fatalf("unexpected reference: %s", ref)
+	}
+	if content, err := os.ReadFile(ref); err != nil || string(content) != "hello world" {
+		t.Fatalf("unexpected content: %q %v", content, err)
+	}
+
+	// identical bodies are stored once
+	if again := write("hello world"); again != ref {
+		t.Fatalf("got %s, expected %s", again, ref)
+	}
+	store.cleaning.Wait()
+
+	// aborted bodies leave no file
+	sink, _ := store.Create(context.Background())
+	_, _ = sink.Write([]byte("aborted"))
+	_ = sink.Abort()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("unexpected files: %v", entries)
+	}
+}
+
+func TestFileBodyStoreCleanup(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := NewFileBodyStore(dir, time.Hour)
+
+	expired := strings.Repeat("a", 64)
+	recent := strings.Repeat("b", 64)
+	old := time.Now().Add(-2 * time.Hour)
+
+	for _, name := range []string{expired, recent, ".tmp-123", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if name != recent {
+			_ = os.Chtimes(filepath.Join(dir, name), old, old)
+		}
+	}
+
+	// an upload in progress, started before the cleanup
+	sink, err := store.Create(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sink.Abort() }()
+	tmp := sink.(*fileBodySink).file.Name()
+	_ = os.Chtimes(tmp, old, old)
+
+	if err := store.Cleanup(); err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+
+	for name, kept := range map[string]bool{expired: false, recent: true, ".tmp-123": true, "notes.txt": true, filepath.Base(tmp): true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if (err == nil) != kept {
+			t.Errorf("%s: kept=%v, expected %v", name, err == nil, kept)
+		}
+	}
+}